@@ -3,22 +3,48 @@ package main
 import (
 	"log"
 	"pomodoro-habit-backend/internal/api"
+	"pomodoro-habit-backend/internal/bruteforce"
+	"pomodoro-habit-backend/internal/cache"
+	"pomodoro-habit-backend/internal/challenges"
 	"pomodoro-habit-backend/internal/config"
 	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/jobs"
+	"pomodoro-habit-backend/internal/leaderboard"
+	"pomodoro-habit-backend/internal/mailer"
+	"pomodoro-habit-backend/internal/oidc"
+	"pomodoro-habit-backend/internal/timer"
+	"pomodoro-habit-backend/internal/utils"
 	"pomodoro-habit-backend/internal/ws"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/google/uuid"
 )
 
 func main() {
 	// Load Configuration
 	cfg := config.LoadConfig()
 
+	// Token Signing Keys
+	if err := utils.LoadSigningKeys(cfg.JWTKeysDir, cfg.JWTActiveKeyID); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+
 	// Connect to Database
 	db.ConnectDB(cfg)
 
+	// Connect to Valkey (optional)
+	cache.ConnectRedis(cfg)
+	bruteforce.Setup(cache.Client)
+	leaderboard.Setup(cache.Client)
+
+	// Outgoing Mail
+	mailer.Setup(cfg)
+
+	// External Identity Providers
+	oidc.Setup(cfg)
+
 	// Initialize Fiber App
 	app := fiber.New(fiber.Config{
 		AppName: "PomoHub API",
@@ -35,6 +61,26 @@ func main() {
 	api.SetupRoutes(app)
 	ws.SetupWebSockets(app)
 
+	// Shared Space Timers
+	timer.Setup(func(spaceID uuid.UUID, state timer.State) {
+		ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeTimerState, state)
+	}, ws.GlobalHub.PresentDuring)
+	if err := timer.Restore(); err != nil {
+		log.Printf("Failed to restore space timers: %v", err)
+	}
+
+	// Space Challenges
+	challenges.Setup(func(spaceID uuid.UUID, update challenges.Update) {
+		msgType := ws.TypeChallengeProgress
+		if update.Challenge.FinalizedAt != nil {
+			msgType = ws.TypeChallengeFinished
+		}
+		ws.GlobalHub.BroadcastToSpace(spaceID, msgType, update)
+	})
+
+	// Background Jobs
+	jobs.Start()
+
 	// Start Server
 	log.Printf("Server starting on port %s", cfg.Port)
 	if err := app.Listen(":" + cfg.Port); err != nil {
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/mailer"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Export My Data (zip archive with one JSON file per kind of record)
+func ExportMyData(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var (
+		todos        []models.Todo
+		habits       []models.Habit
+		sessions     []models.PomodoroSession
+		posts        []models.Post
+		messages     []models.Message
+		friendships  []models.Friend
+		memberships  []models.SpaceMember
+		logins       []models.Session
+		identities   []models.UserIdentity
+		accessTokens []models.PersonalAccessToken
+		joinRequests []models.SpaceJoinRequest
+		taskComments []models.SpaceTaskComment
+		rsvps        []models.SpaceEventRSVP
+		templates    []models.SpaceTemplate
+	)
+	queries := []error{
+		db.DB.Where("user_id = ?", userID).Find(&todos).Error,
+		db.DB.Preload("Logs").Where("user_id = ?", userID).Find(&habits).Error,
+		db.DB.Where("user_id = ?", userID).Find(&sessions).Error,
+		db.DB.Where("user_id = ?", userID).Find(&posts).Error,
+		db.DB.Where("sender_id = ?", userID).Find(&messages).Error,
+		db.DB.Where("user_id = ? OR friend_id = ?", userID, userID).Find(&friendships).Error,
+		db.DB.Where("user_id = ?", userID).Find(&memberships).Error,
+		db.DB.Where("user_id = ?", userID).Find(&logins).Error,
+		db.DB.Where("user_id = ?", userID).Find(&identities).Error,
+		db.DB.Where("user_id = ?", userID).Find(&accessTokens).Error,
+		db.DB.Where("user_id = ?", userID).Find(&joinRequests).Error,
+		db.DB.Where("user_id = ?", userID).Find(&taskComments).Error,
+		db.DB.Where("user_id = ?", userID).Find(&rsvps).Error,
+		db.DB.Where("owner_id = ?", userID).Find(&templates).Error,
+	}
+	for _, err := range queries {
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+		}
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"todos.json", todos},
+		{"habits.json", habits},
+		{"pomodoro_sessions.json", sessions},
+		{"posts.json", posts},
+		{"messages.json", messages},
+		{"friendships.json", friendships},
+		{"space_memberships.json", memberships},
+		{"sessions.json", logins},
+		{"linked_accounts.json", identities},
+		{"access_tokens.json", accessTokens},
+		{"space_join_requests.json", joinRequests},
+		{"task_comments.json", taskComments},
+		{"event_rsvps.json", rsvps},
+		{"space_templates.json", templates},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not export data"})
+	}
+
+	filename := "pomohub-export-" + user.Username + "-" + time.Now().Format("2006-01-02") + ".zip"
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Send(buf.Bytes())
+}
+
+// Schedule Account Deletion (purged after the grace period unless cancelled)
+func ScheduleAccountDeletion(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	sessionID, _ := getSessionID(c)
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Account deletion is already scheduled", "deletion_scheduled_at": user.DeletionScheduledAt})
+	}
+
+	// Accounts created through an identity provider may not have a password
+	if user.PasswordHash != "" && !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password is incorrect"})
+	}
+	if user.TOTPEnabled && !verifySecondFactor(user, req.Code, req.RecoveryCode) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	cfg := config.LoadConfig()
+	deleteAt := time.Now().Add(cfg.AccountDeletionGrace)
+	if err := db.DB.Model(&user).Update("deletion_scheduled_at", deleteAt).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not schedule account deletion"})
+	}
+
+	// Sign out everywhere else and stop integrations; this device can still cancel
+	if err := revokeSessions(userID, sessionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not sign out other sessions"})
+	}
+	db.DB.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+
+	mailer.SendAsync(mailer.Message{
+		To:      user.Email,
+		Subject: "Your PomoHub account will be deleted",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"We received a request to delete your PomoHub account. Your account and all of its data will be permanently erased on " +
+			deleteAt.Format("January 2, 2006") + ".\n\n" +
+			"Changed your mind? Sign in before then and cancel the deletion from your account settings.\n",
+	})
+
+	return c.JSON(fiber.Map{
+		"message":               "Account deletion scheduled",
+		"deletion_scheduled_at": deleteAt,
+	})
+}
+
+// Cancel Account Deletion
+func CancelAccountDeletion(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	result := db.DB.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not cancel account deletion"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Account deletion is not scheduled"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Account deletion cancelled"})
+}
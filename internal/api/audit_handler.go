@@ -0,0 +1,92 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const auditPageSize = 50
+
+// Get Space Audit Log (admins only)
+func GetSpaceAudit(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewAudit); err != nil {
+		return authorizeError(c, err, "Only admins can view the audit log")
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", auditPageSize)
+	if limit < 1 || limit > 100 {
+		limit = auditPageSize
+	}
+
+	query := db.DB.Model(&models.SpaceAuditLog{}).Where("space_id = ?", spaceID)
+
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if actor := c.Query("actor_id"); actor != "" {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid actor ID"})
+		}
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if target := c.Query("target_id"); target != "" {
+		targetID, err := uuid.Parse(target)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid target ID"})
+		}
+		query = query.Where("target_id = ?", targetID)
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Since must be an RFC 3339 timestamp"})
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Until must be an RFC 3339 timestamp"})
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	// Share the filters between the count and the page
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch audit log"})
+	}
+
+	var entries []models.SpaceAuditLog
+	if err := query.Preload("Actor").
+		Order("created_at desc").
+		Limit(limit).Offset((page - 1) * limit).
+		Find(&entries).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch audit log"})
+	}
+
+	return c.JSON(fiber.Map{"entries": entries, "page": page, "limit": limit, "total": total})
+}
@@ -1,34 +1,99 @@
 package api
 
 import (
+	"log"
+	"math"
+	"net/mail"
+	"pomodoro-habit-backend/internal/bruteforce"
 	"pomodoro-habit-backend/internal/config"
 	"pomodoro-habit-backend/internal/db"
 	"pomodoro-habit-backend/internal/models"
 	"pomodoro-habit-backend/internal/utils"
 	"regexp"
+	"strconv"
 	"time"
 	"unicode"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type RegisterRequest struct {
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	BirthDate string `json:"birth_date"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	BirthDate  string `json:"birth_date"`
+	DeviceName string `json:"device_name"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresIn    int64       `json:"expires_in"` // Access token lifetime in seconds
+	User         models.User `json:"user"`
+}
+
+// tooManyAttempts answers a locked-out login attempt.
+func tooManyAttempts(c *fiber.Ctx, retryAfter time.Duration) error {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       "Too many failed attempts, please try again later",
+		"retry_after": seconds,
+	})
+}
+
+// issueTokens starts a new session for the user and returns an access token
+// bound to it together with the session's refresh token.
+func issueTokens(c *fiber.Ctx, user models.User, deviceName string) (*AuthResponse, error) {
+	cfg := config.LoadConfig()
+
+	refreshToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if deviceName == "" {
+		deviceName = deviceNameFromUserAgent(userAgent)
+	}
+
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		DeviceName:       deviceName,
+		UserAgent:        userAgent,
+		IPAddress:        c.IP(),
+		LastSeenAt:       time.Now(),
+		ExpiresAt:        time.Now().Add(cfg.RefreshTokenTTL),
+	}
+	if err := db.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateAccessToken(user.ID, session.ID, cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(cfg.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
 }
 
 func validatePassword(s string) bool {
@@ -65,6 +130,11 @@ func Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing required fields"})
 	}
 
+	// Email Validation
+	if addr, err := mail.ParseAddress(req.Email); err != nil || addr.Address != req.Email {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
+	}
+
 	// Username Validation (English letters only)
 	if !regexp.MustCompile(`^[a-zA-Z0-9_]+$`).MatchString(req.Username) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Username must contain only English letters, numbers, and underscores"})
@@ -72,7 +142,7 @@ func Register(c *fiber.Ctx) error {
 
 	// Password Validation
 	if !validatePassword(req.Password) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password must be at least 8 characters long and contain at least one uppercase letter, one lowercase letter, and one special character"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": passwordRequirementsError})
 	}
 
 	// Check if user exists
@@ -112,17 +182,17 @@ func Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create user"})
 	}
 
-	// Generate Token
-	cfg := config.LoadConfig()
-	token, err := utils.GenerateJWT(newUser.ID, cfg.JWTSecret)
+	if err := sendVerificationEmail(newUser); err != nil {
+		log.Printf("Could not send verification email to %s: %v", newUser.Email, err)
+	}
+
+	// Generate Tokens
+	resp, err := issueTokens(c, newUser, req.DeviceName)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(AuthResponse{
-		Token: token,
-		User:  newUser,
-	})
+	return c.Status(fiber.StatusCreated).JSON(resp)
 }
 
 func Login(c *fiber.Ctx) error {
@@ -131,24 +201,128 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	accountKey := bruteforce.AccountKey(req.Email)
+	ipKey := bruteforce.IPKey(c.IP())
+	if retryAfter := bruteforce.RetryAfter(c.Context(), accountKey, ipKey); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+
 	var user models.User
-	if result := db.DB.Where("email = ?", req.Email).First(&user); result.Error != nil {
+	result := db.DB.Where("email = ?", req.Email).First(&user)
+	if result.Error != nil || !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+		lockout := bruteforce.Fail(c.Context(), bruteforce.AccountPolicy, accountKey)
+		if ipLockout := bruteforce.Fail(c.Context(), bruteforce.IPPolicy, ipKey); ipLockout > lockout {
+			lockout = ipLockout
+		}
+		if lockout > 0 {
+			return tooManyAttempts(c, lockout)
+		}
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 
-	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
+	bruteforce.Succeed(c.Context(), accountKey)
+
+	return completeLogin(c, user, req.DeviceName)
+}
+
+// Get JWKS (public keys for verifying access tokens)
+func GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(utils.JWKS())
+}
+
+// Refresh Token (rotates the refresh token and issues a new access token)
+func RefreshToken(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	// Generate Token
 	cfg := config.LoadConfig()
-	token, err := utils.GenerateJWT(user.ID, cfg.JWTSecret)
+	tokenHash := utils.HashToken(req.RefreshToken)
+
+	var session models.Session
+	if err := db.DB.Where("refresh_token_hash = ?", tokenHash).First(&session).Error; err != nil {
+		// A rotated-out token being replayed means it leaked; kill the session it belonged to.
+		var reused models.Session
+		if db.DB.Where("previous_token_hash = ? AND revoked_at IS NULL", tokenHash).First(&reused).RowsAffected > 0 {
+			revokeSession(reused.ID)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	if !session.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session has expired or been revoked"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, session.UserID).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	newRefreshToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+	}
+
+	// Conditional update so two concurrent refreshes with the same token can't both succeed
+	result := db.DB.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, tokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  utils.HashToken(newRefreshToken),
+			"previous_token_hash": tokenHash,
+			"user_agent":          c.Get(fiber.HeaderUserAgent),
+			"ip_address":          c.IP(),
+			"last_seen_at":        time.Now(),
+			"expires_at":          time.Now().Add(cfg.RefreshTokenTTL),
+		})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not refresh session"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	token, err := utils.GenerateAccessToken(user.ID, session.ID, cfg.AccessTokenTTL)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
 	}
 
 	return c.JSON(AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    int64(cfg.AccessTokenTTL.Seconds()),
+		User:         user,
 	})
 }
+
+// Logout (revokes the session owning the given refresh token)
+func Logout(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var session models.Session
+	if result := db.DB.Where("refresh_token_hash = ?", utils.HashToken(req.RefreshToken)).First(&session); result.RowsAffected > 0 {
+		if err := revokeSession(session.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not log out"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": "Logged out successfully"})
+}
+
+// Logout All (revokes every session of the current user)
+func LogoutAll(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := revokeSessions(userID, uuid.Nil); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not log out"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Logged out of all sessions"})
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Past sessions stay in the feed this long so calendars keep recent history
+const calendarHistory = 30 * 24 * time.Hour
+
+const icsTimeFormat = "20060102T150405Z"
+
+func calendarURL(token string) string {
+	cfg := config.LoadConfig()
+	return strings.TrimRight(cfg.AppURL, "/") + "/api/v1/calendar/" + token + ".ics"
+}
+
+// Get Space Calendar feed URL, creating the feed on first use
+func GetSpaceCalendar(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var space models.Space
+	if err := db.DB.Select("id", "calendar_token").First(&space, spaceID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
+	}
+
+	if space.CalendarToken == nil {
+		token, err := utils.GenerateOpaqueToken(24)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create calendar feed"})
+		}
+		// Another request may have created it meanwhile, keep whichever came first
+		if err := db.DB.Model(&models.Space{}).Where("id = ? AND calendar_token IS NULL", spaceID).
+			Update("calendar_token", token).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create calendar feed"})
+		}
+		if err := db.DB.Select("id", "calendar_token").First(&space, spaceID).Error; err != nil || space.CalendarToken == nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create calendar feed"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"url": calendarURL(*space.CalendarToken)})
+}
+
+// Reset Space Calendar feed URL (admins only), e.g. after it leaked
+func ResetSpaceCalendar(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.EditSettings); err != nil {
+		return authorizeError(c, err, "Only admins can reset the calendar feed")
+	}
+
+	token, err := utils.GenerateOpaqueToken(24)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset calendar feed"})
+	}
+	if err := db.DB.Model(&models.Space{}).Where("id = ?", spaceID).Update("calendar_token", token).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset calendar feed"})
+	}
+
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditCalendarReset, "space", &spaceID, nil, nil)
+
+	return c.JSON(fiber.Map{"url": calendarURL(token)})
+}
+
+// Get Calendar Feed (public, authenticated by the token in the URL)
+func GetCalendarFeed(c *fiber.Ctx) error {
+	token := strings.TrimSuffix(c.Params("token"), ".ics")
+	if token == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Calendar not found"})
+	}
+
+	var space models.Space
+	if err := db.DB.Where("calendar_token = ?", token).First(&space).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Calendar not found"})
+	}
+
+	since := time.Now().Add(-calendarHistory)
+	var events []models.SpaceEvent
+	if err := db.DB.Where("space_id = ?", space.ID).
+		Where("(recurrence = '' AND starts_at >= ?) OR (recurrence <> '' AND (recurrence_until IS NULL OR recurrence_until >= ?))", since, since).
+		Order("starts_at").
+		Find(&events).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not build calendar"})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="space.ics"`)
+	return c.SendString(buildICS(space, events))
+}
+
+// buildICS renders a space's events as an iCalendar (RFC 5545) document.
+func buildICS(space models.Space, events []models.SpaceEvent) string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		b.WriteString(foldICSLine(fmt.Sprintf(format, args...)))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//PomoHub//Space Schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", escapeICSText(space.Name))
+	for _, event := range events {
+		start := event.StartsAt.UTC()
+		line("BEGIN:VEVENT")
+		line("UID:%s@pomohub", event.ID)
+		line("DTSTAMP:%s", event.UpdatedAt.UTC().Format(icsTimeFormat))
+		line("DTSTART:%s", start.Format(icsTimeFormat))
+		line("DTEND:%s", start.Add(time.Duration(event.DurationMinutes)*time.Minute).Format(icsTimeFormat))
+		line("SUMMARY:%s", escapeICSText(event.Title))
+		if event.Description != "" {
+			line("DESCRIPTION:%s", escapeICSText(event.Description))
+		}
+		if event.Recurrence != models.EventRecurrenceNone {
+			rule := "FREQ=" + strings.ToUpper(event.Recurrence)
+			if event.RecurrenceUntil != nil {
+				rule += ";UNTIL=" + event.RecurrenceUntil.UTC().Format(icsTimeFormat)
+			}
+			line("RRULE:%s", rule)
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a TEXT value as RFC 5545 section 3.3.11 requires.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits content lines longer than 75 octets, continuing them on
+// lines that start with a space. It never splits a UTF-8 sequence.
+func foldICSLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
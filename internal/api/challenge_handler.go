@@ -0,0 +1,195 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/challenges"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Get Space Challenges (?status=upcoming, active or finished)
+func GetSpaceChallenges(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	now := time.Now()
+	query := db.DB.Where("space_id = ?", spaceID)
+	switch c.Query("status") {
+	case "":
+	case "upcoming":
+		query = query.Where("starts_at > ?", now)
+	case "active":
+		query = query.Where("starts_at <= ? AND finalized_at IS NULL", now)
+	case "finished":
+		query = query.Where("finalized_at IS NOT NULL")
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status must be 'upcoming', 'active' or 'finished'"})
+	}
+
+	var spaceChallenges []models.SpaceChallenge
+	if err := query.Order("ends_at desc").Preload("CreatedBy").Find(&spaceChallenges).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch challenges"})
+	}
+
+	return c.JSON(spaceChallenges)
+}
+
+// Get Space Challenge with its progress, or final results once it is over
+func GetSpaceChallenge(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	challengeID, err := uuid.Parse(c.Params("challengeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid challenge ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var challenge models.SpaceChallenge
+	if err := db.DB.Where("id = ? AND space_id = ?", challengeID, spaceID).Preload("CreatedBy").First(&challenge).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Challenge not found"})
+	}
+
+	if challenge.Results != nil {
+		return c.JSON(challenges.Update{Challenge: challenge, Progress: *challenge.Results})
+	}
+
+	progress, err := challenges.Progress(db.DB, challenge)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not compute progress"})
+	}
+
+	return c.JSON(challenges.Update{Challenge: challenge, Progress: progress})
+}
+
+// Create Space Challenge (moderators and above)
+func CreateSpaceChallenge(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		Title       string    `json:"title"`
+		Description string    `json:"description"`
+		Metric      string    `json:"metric"`
+		Target      int64     `json:"target"`
+		StartsAt    time.Time `json:"starts_at"`
+		EndsAt      time.Time `json:"ends_at"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
+	}
+	if len(req.Title) > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title can be at most 200 characters long"})
+	}
+	if !challenges.IsValidMetric(req.Metric) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Metric must be 'focus_minutes', 'pomodoros' or 'habit_checkins'"})
+	}
+	if req.Target <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Target must be positive"})
+	}
+	if req.StartsAt.IsZero() {
+		req.StartsAt = time.Now()
+	}
+	if !req.EndsAt.After(req.StartsAt) || !req.EndsAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The challenge must end in the future and after it starts"})
+	}
+	if req.EndsAt.Sub(req.StartsAt) > 366*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Challenges can last at most a year"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageGoals); err != nil {
+		return authorizeError(c, err, "Only moderators can create challenges")
+	}
+
+	challenge := models.SpaceChallenge{
+		SpaceID:     spaceID,
+		CreatedByID: userID,
+		Title:       req.Title,
+		Description: strings.TrimSpace(req.Description),
+		Metric:      req.Metric,
+		Target:      req.Target,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+	}
+	if err := db.DB.Omit("CreatedBy").Create(&challenge).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create challenge"})
+	}
+
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditChallengeCreated, "challenge", &challenge.ID, nil,
+		map[string]interface{}{"title": challenge.Title, "metric": challenge.Metric, "target": challenge.Target})
+
+	return c.Status(fiber.StatusCreated).JSON(challenge)
+}
+
+// Delete Space Challenge (moderators and above)
+func DeleteSpaceChallenge(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	challengeID, err := uuid.Parse(c.Params("challengeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid challenge ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageGoals); err != nil {
+		return authorizeError(c, err, "Only moderators can delete challenges")
+	}
+
+	var challenge models.SpaceChallenge
+	if err := db.DB.Where("id = ? AND space_id = ?", challengeID, spaceID).First(&challenge).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Challenge not found"})
+	}
+
+	if err := db.DB.Delete(&challenge).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete challenge"})
+	}
+
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditChallengeDeleted, "challenge", &challenge.ID,
+		map[string]interface{}{"title": challenge.Title, "metric": challenge.Metric, "target": challenge.Target}, nil)
+
+	return c.JSON(fiber.Map{"message": "Challenge deleted successfully"})
+}
@@ -2,7 +2,9 @@ package api
 
 import (
 	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
 	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
 	"pomodoro-habit-backend/internal/ws"
 	"time"
 
@@ -35,10 +37,9 @@ func SendMessage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Content cannot be empty"})
 	}
 
-	// Check if user is member of space
-	var membership models.SpaceMember
-	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, senderID).First(&membership).Error; err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this space"})
+	// Check if user may chat in the space
+	if _, err := permissions.Authorize(spaceID, senderID, permissions.SendMessages); err != nil {
+		return authorizeError(c, err, "You cannot send messages in this space")
 	}
 
 	message := models.Message{
@@ -73,14 +74,23 @@ func GetMessages(c *fiber.Ctx) error {
 	}
 
 	// Check membership
-	var membership models.SpaceMember
-	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&membership).Error; err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	// History older than the owner's plan retains is hidden
+	cutoff, err := entitlements.MessageCutoff(db.DB, spaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch messages"})
 	}
 
 	var messages []models.Message
 	// Pagination could be added here
-	err = db.DB.Where("space_id = ?", spaceID).
+	query := db.DB.Where("space_id = ?", spaceID)
+	if cutoff != nil {
+		query = query.Where("created_at >= ?", *cutoff)
+	}
+	err = query.
 		Order("created_at desc").
 		Limit(50).
 		Preload("Sender"). // Load sender details
@@ -92,3 +102,156 @@ func GetMessages(c *fiber.Ctx) error {
 
 	return c.JSON(messages)
 }
+
+// memberRole returns the role of a user in a space, or "" when they are no
+// longer a member, which every role outranks.
+func memberRole(spaceID, userID uuid.UUID) string {
+	var member models.SpaceMember
+	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&member).Error; err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// Delete Message (own messages, or anyone's for moderators and above)
+func DeleteMessage(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	messageID, err := uuid.Parse(c.Params("messageId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace)
+	if err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var message models.Message
+	if err := db.DB.Where("id = ? AND space_id = ?", messageID, spaceID).First(&message).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
+	}
+
+	if message.SenderID != userID {
+		if !permissions.Can(membership.Role, permissions.DeleteMessages) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only delete your own messages"})
+		}
+		if !permissions.Outranks(membership.Role, memberRole(spaceID, message.SenderID)) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot delete messages of a member with an equal or higher role"})
+		}
+	}
+
+	if err := db.DB.Delete(&message).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete message"})
+	}
+
+	// Only who wrote it; the text goes away with the message and its sender's account
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditMessageDeleted, "message", &message.ID,
+		map[string]interface{}{"sender_id": message.SenderID}, nil)
+
+	ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeMessageDeleted, fiber.Map{"message_id": message.ID})
+
+	return c.JSON(fiber.Map{"message": "Message deleted successfully"})
+}
+
+// Get Pinned Messages
+func GetPinnedMessages(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	cutoff, err := entitlements.MessageCutoff(db.DB, spaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch messages"})
+	}
+
+	var messages []models.Message
+	query := db.DB.Where("space_id = ? AND pinned_at IS NOT NULL", spaceID)
+	if cutoff != nil {
+		query = query.Where("created_at >= ?", *cutoff)
+	}
+	if err := query.
+		Order("pinned_at desc").
+		Preload("Sender").
+		Find(&messages).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch messages"})
+	}
+
+	return c.JSON(messages)
+}
+
+// Pin Message
+func PinMessage(c *fiber.Ctx) error {
+	return setMessagePinned(c, true)
+}
+
+// Unpin Message
+func UnpinMessage(c *fiber.Ctx) error {
+	return setMessagePinned(c, false)
+}
+
+func setMessagePinned(c *fiber.Ctx, pinned bool) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	messageID, err := uuid.Parse(c.Params("messageId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.PinMessages); err != nil {
+		return authorizeError(c, err, "Only moderators can pin messages")
+	}
+
+	var message models.Message
+	if err := db.DB.Where("id = ? AND space_id = ?", messageID, spaceID).First(&message).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
+	}
+
+	msgType := ws.TypeMessageUnpinned
+	updates := map[string]interface{}{"pinned_at": nil, "pinned_by_id": nil}
+	message.PinnedAt, message.PinnedByID = nil, nil
+	if pinned {
+		now := time.Now()
+		msgType = ws.TypeMessagePinned
+		updates = map[string]interface{}{"pinned_at": now, "pinned_by_id": userID}
+		message.PinnedAt, message.PinnedByID = &now, &userID
+	}
+
+	if err := db.DB.Model(&message).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update message"})
+	}
+
+	action := models.AuditMessageUnpinned
+	if pinned {
+		action = models.AuditMessagePinned
+	}
+	models.RecordAudit(db.DB, spaceID, &userID, action, "message", &message.ID, nil, nil)
+
+	ws.GlobalHub.BroadcastToSpace(spaceID, msgType, message)
+
+	return c.JSON(message)
+}
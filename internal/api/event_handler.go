@@ -0,0 +1,476 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultEventWindow = 30 * 24 * time.Hour
+	maxEventWindow     = 90 * 24 * time.Hour
+)
+
+// EventOccurrence is one scheduled occurrence of an event with its RSVPs.
+type EventOccurrence struct {
+	EventID        uuid.UUID      `json:"event_id"`
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	StartsAt       time.Time      `json:"starts_at"`
+	EndsAt         time.Time      `json:"ends_at"`
+	Recurrence     string         `json:"recurrence"`
+	AutoStartTimer bool           `json:"auto_start_timer"`
+	CreatedByID    uuid.UUID      `json:"created_by_id"`
+	RSVPs          map[string]int `json:"rsvps"`
+	MyResponse     string         `json:"my_response,omitempty"`
+}
+
+// validateEvent checks a new or edited event and returns what is wrong with
+// it, or "" when it is fine.
+func validateEvent(event *models.SpaceEvent) string {
+	if event.Title == "" {
+		return "Title is required"
+	}
+	if len(event.Title) > 200 {
+		return "Title can be at most 200 characters long"
+	}
+	if event.StartsAt.IsZero() {
+		return "Start time is required"
+	}
+	if event.DurationMinutes < 5 || event.DurationMinutes > 480 {
+		return "Duration must be between 5 and 480 minutes"
+	}
+	switch event.Recurrence {
+	case models.EventRecurrenceNone, models.EventRecurrenceDaily, models.EventRecurrenceWeekly:
+	default:
+		return "Recurrence must be '', 'daily' or 'weekly'"
+	}
+	if event.RecurrenceUntil != nil && event.RecurrenceUntil.Before(event.StartsAt) {
+		return "Recurrence cannot end before the event starts"
+	}
+	return ""
+}
+
+// canEditEvent reports whether a member may change or delete an event: its
+// creator while they may still schedule events, or a moderator and above.
+func canEditEvent(membership models.SpaceMember, event models.SpaceEvent) bool {
+	if permissions.Can(membership.Role, permissions.ManageEvents) {
+		return true
+	}
+	return event.CreatedByID == membership.UserID && permissions.Can(membership.Role, permissions.ScheduleEvents)
+}
+
+// Get Space Events, expanded into occurrences within ?from= and ?to=
+func GetSpaceEvents(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	from := time.Now()
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.RFC3339, raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be an RFC 3339 timestamp"})
+		}
+	}
+	to := from.Add(defaultEventWindow)
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.RFC3339, raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to must be an RFC 3339 timestamp"})
+		}
+	}
+	if !to.After(from) || to.Sub(from) > maxEventWindow {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to must be after from and at most 90 days later"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var events []models.SpaceEvent
+	if err := db.DB.Where("space_id = ? AND starts_at < ?", spaceID, to).
+		Where("(recurrence = '' AND starts_at >= ?) OR (recurrence <> '' AND (recurrence_until IS NULL OR recurrence_until >= ?))", from, from).
+		Find(&events).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch events"})
+	}
+
+	eventIDs := make([]uuid.UUID, 0, len(events))
+	for _, event := range events {
+		eventIDs = append(eventIDs, event.ID)
+	}
+	var rsvps []models.SpaceEventRSVP
+	if len(eventIDs) > 0 {
+		if err := db.DB.Where("event_id IN ? AND occurs_at >= ? AND occurs_at < ?", eventIDs, from, to).
+			Find(&rsvps).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch events"})
+		}
+	}
+
+	type occurrenceKey struct {
+		eventID  uuid.UUID
+		occursAt int64
+	}
+	counts := make(map[occurrenceKey]map[string]int)
+	mine := make(map[occurrenceKey]string)
+	for _, rsvp := range rsvps {
+		key := occurrenceKey{rsvp.EventID, rsvp.OccursAt.Unix()}
+		if counts[key] == nil {
+			counts[key] = make(map[string]int)
+		}
+		counts[key][rsvp.Response]++
+		if rsvp.UserID == userID {
+			mine[key] = rsvp.Response
+		}
+	}
+
+	occurrences := []EventOccurrence{}
+	for _, event := range events {
+		for _, startsAt := range event.Occurrences(from, to) {
+			key := occurrenceKey{event.ID, startsAt.Unix()}
+			rsvpCounts := map[string]int{models.RSVPGoing: 0, models.RSVPMaybe: 0, models.RSVPDeclined: 0}
+			for response, count := range counts[key] {
+				rsvpCounts[response] = count
+			}
+			occurrences = append(occurrences, EventOccurrence{
+				EventID:        event.ID,
+				Title:          event.Title,
+				Description:    event.Description,
+				StartsAt:       startsAt,
+				EndsAt:         startsAt.Add(time.Duration(event.DurationMinutes) * time.Minute),
+				Recurrence:     event.Recurrence,
+				AutoStartTimer: event.AutoStartTimer,
+				CreatedByID:    event.CreatedByID,
+				RSVPs:          rsvpCounts,
+				MyResponse:     mine[key],
+			})
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].StartsAt.Before(occurrences[j].StartsAt)
+	})
+
+	return c.JSON(occurrences)
+}
+
+// Create Space Event
+func CreateSpaceEvent(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		Title           string     `json:"title"`
+		Description     string     `json:"description"`
+		StartsAt        time.Time  `json:"starts_at"`
+		DurationMinutes int        `json:"duration_minutes"`
+		Recurrence      string     `json:"recurrence"`
+		RecurrenceUntil *time.Time `json:"recurrence_until"`
+		AutoStartTimer  *bool      `json:"auto_start_timer"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ScheduleEvents); err != nil {
+		return authorizeError(c, err, "Guests cannot schedule sessions")
+	}
+
+	// Occurrences start on the minute so RSVPs match them exactly
+	event := models.SpaceEvent{
+		SpaceID:         spaceID,
+		CreatedByID:     userID,
+		Title:           strings.TrimSpace(req.Title),
+		Description:     strings.TrimSpace(req.Description),
+		StartsAt:        req.StartsAt.UTC().Truncate(time.Minute),
+		DurationMinutes: req.DurationMinutes,
+		Recurrence:      req.Recurrence,
+		RecurrenceUntil: req.RecurrenceUntil,
+		AutoStartTimer:  req.AutoStartTimer == nil || *req.AutoStartTimer,
+	}
+	if msg := validateEvent(&event); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if !event.StartsAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sessions must be scheduled in the future"})
+	}
+
+	// Create with Select so that an explicit false is not replaced by the default
+	if err := db.DB.Select("*").Omit("CreatedBy").Create(&event).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not schedule session"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(event)
+}
+
+// Update Space Event (its creator, or moderators and above)
+func UpdateSpaceEvent(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	eventID, err := uuid.Parse(c.Params("eventId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+
+	type Request struct {
+		Title           *string    `json:"title"`
+		Description     *string    `json:"description"`
+		StartsAt        *time.Time `json:"starts_at"`
+		DurationMinutes *int       `json:"duration_minutes"`
+		Recurrence      *string    `json:"recurrence"`
+		RecurrenceUntil *time.Time `json:"recurrence_until"`
+		AutoStartTimer  *bool      `json:"auto_start_timer"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace)
+	if err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var event models.SpaceEvent
+	if err := db.DB.Where("id = ? AND space_id = ?", eventID, spaceID).First(&event).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	}
+	if !canEditEvent(membership, event) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only edit sessions you scheduled"})
+	}
+
+	previousStart, previousRecurrence := event.StartsAt, event.Recurrence
+	if req.Title != nil {
+		event.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		event.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.StartsAt != nil {
+		event.StartsAt = req.StartsAt.UTC().Truncate(time.Minute)
+	}
+	if req.DurationMinutes != nil {
+		event.DurationMinutes = *req.DurationMinutes
+	}
+	if req.Recurrence != nil {
+		event.Recurrence = *req.Recurrence
+	}
+	if req.RecurrenceUntil != nil {
+		event.RecurrenceUntil = req.RecurrenceUntil
+	}
+	if req.AutoStartTimer != nil {
+		event.AutoStartTimer = *req.AutoStartTimer
+	}
+	if msg := validateEvent(&event); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	rescheduled := !event.StartsAt.Equal(previousStart) || event.Recurrence != previousRecurrence
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("CreatedBy").Save(&event).Error; err != nil {
+			return err
+		}
+		if !rescheduled {
+			return nil
+		}
+		// Answers for upcoming occurrences no longer match the new schedule
+		return tx.Where("event_id = ? AND occurs_at >= ?", event.ID, time.Now()).Delete(&models.SpaceEventRSVP{}).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update session"})
+	}
+
+	return c.JSON(event)
+}
+
+// Delete Space Event (its creator, or moderators and above)
+func DeleteSpaceEvent(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	eventID, err := uuid.Parse(c.Params("eventId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace)
+	if err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var event models.SpaceEvent
+	if err := db.DB.Where("id = ? AND space_id = ?", eventID, spaceID).First(&event).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	}
+	if !canEditEvent(membership, event) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only cancel sessions you scheduled"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", event.ID).Delete(&models.SpaceEventRSVP{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&event).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, spaceID, &userID, models.AuditEventDeleted, "event", &event.ID,
+			map[string]interface{}{"title": event.Title, "created_by_id": event.CreatedByID, "starts_at": event.StartsAt}, nil)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not cancel session"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Session cancelled successfully"})
+}
+
+// RSVP to an occurrence of a Space Event (the next one unless occurs_at is given)
+func RSVPSpaceEvent(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	eventID, err := uuid.Parse(c.Params("eventId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+
+	type Request struct {
+		Response string     `json:"response"`
+		OccursAt *time.Time `json:"occurs_at"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	switch req.Response {
+	case models.RSVPGoing, models.RSVPMaybe, models.RSVPDeclined:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Response must be 'going', 'maybe' or 'declined'"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var event models.SpaceEvent
+	if err := db.DB.Where("id = ? AND space_id = ?", eventID, spaceID).First(&event).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	}
+
+	now := time.Now()
+	var occursAt time.Time
+	if req.OccursAt != nil {
+		if !event.IsOccurrence(*req.OccursAt) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The session does not take place at that time"})
+		}
+		occursAt = req.OccursAt.UTC().Truncate(time.Minute)
+	} else {
+		// The next occurrence that has not ended yet
+		ongoing := now.Add(-time.Duration(event.DurationMinutes) * time.Minute)
+		next := event.Occurrences(ongoing, ongoing.Add(maxEventWindow))
+		if len(next) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The session has no upcoming occurrences"})
+		}
+		occursAt = next[0]
+	}
+	if !occursAt.Add(time.Duration(event.DurationMinutes) * time.Minute).After(now) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The session is already over"})
+	}
+
+	rsvp := models.SpaceEventRSVP{
+		SpaceID:  spaceID,
+		EventID:  event.ID,
+		UserID:   userID,
+		OccursAt: occursAt,
+		Response: req.Response,
+	}
+	if err := db.DB.Omit("User").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}, {Name: "user_id"}, {Name: "occurs_at"}},
+		DoUpdates: clause.AssignmentColumns([]string{"response", "updated_at"}),
+	}).Create(&rsvp).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save RSVP"})
+	}
+
+	return c.JSON(rsvp)
+}
+
+// Get RSVPs for an occurrence of a Space Event
+func GetSpaceEventRSVPs(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	eventID, err := uuid.Parse(c.Params("eventId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+	occursAt, err := time.Parse(time.RFC3339, c.Query("occurs_at"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "occurs_at must be an RFC 3339 timestamp"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var event models.SpaceEvent
+	if err := db.DB.Select("id").Where("id = ? AND space_id = ?", eventID, spaceID).First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch RSVPs"})
+	}
+
+	var rsvps []models.SpaceEventRSVP
+	if err := db.DB.Where("event_id = ? AND occurs_at = ?", event.ID, occursAt.UTC()).
+		Order("updated_at asc").
+		Preload("User").
+		Find(&rsvps).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch RSVPs"})
+	}
+
+	return c.JSON(rsvps)
+}
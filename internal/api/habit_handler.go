@@ -1,6 +1,7 @@
 package api
 
 import (
+	"pomodoro-habit-backend/internal/challenges"
 	"pomodoro-habit-backend/internal/db"
 	"pomodoro-habit-backend/internal/models"
 	"time"
@@ -82,6 +83,7 @@ func ToggleHabit(c *fiber.Ctx) error {
 	if result.RowsAffected > 0 {
 		// Already completed today, un-complete it
 		db.DB.Delete(&log)
+		go challenges.Notify(userID)
 		return c.JSON(fiber.Map{"status": "uncompleted"})
 	} else {
 		// Complete it
@@ -90,6 +92,7 @@ func ToggleHabit(c *fiber.Ctx) error {
 			Date:    today,
 		}
 		db.DB.Create(&newLog)
+		go challenges.Notify(userID)
 		return c.JSON(fiber.Map{"status": "completed"})
 	}
 }
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/leaderboard"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InviteResponse struct {
+	models.SpaceInvite
+	URL string `json:"url"`
+}
+
+func inviteURL(code string) string {
+	cfg := config.LoadConfig()
+	return strings.TrimRight(cfg.FrontendURL, "/") + "/invite/" + code
+}
+
+// Create Invite (admins only)
+func CreateInvite(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		Role           string `json:"role"`
+		MaxUses        int    `json:"max_uses"`         // 0 = unlimited
+		ExpiresInHours int    `json:"expires_in_hours"` // 0 = never expires
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, userID, permissions.ManageMembers)
+	if err != nil {
+		return authorizeError(c, err, "Only admins can create invites")
+	}
+
+	if req.Role == "" {
+		req.Role = models.SpaceRoleMember
+	}
+	if !permissions.IsValidRole(req.Role) || req.Role == models.SpaceRoleOwner {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Role must be 'admin', 'moderator', 'member' or 'guest'"})
+	}
+	if !permissions.Outranks(membership.Role, req.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only invite with roles below your own"})
+	}
+	if req.MaxUses < 0 || req.ExpiresInHours < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Max uses and expiry must not be negative"})
+	}
+
+	code, err := utils.GenerateOpaqueToken(8)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate invite code"})
+	}
+
+	invite := models.SpaceInvite{
+		SpaceID:     spaceID,
+		Code:        code,
+		CreatedByID: userID,
+		Role:        req.Role,
+		MaxUses:     req.MaxUses,
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := db.DB.Create(&invite).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create invite"})
+	}
+
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditInviteCreated, "invite", &invite.ID, nil,
+		map[string]interface{}{"role": invite.Role, "max_uses": invite.MaxUses, "expires_at": invite.ExpiresAt})
+
+	return c.Status(fiber.StatusCreated).JSON(InviteResponse{SpaceInvite: invite, URL: inviteURL(invite.Code)})
+}
+
+// Get Invites (outstanding invites of a space, admins only)
+func GetInvites(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageMembers); err != nil {
+		return authorizeError(c, err, "Only admins can view invites")
+	}
+
+	var invites []models.SpaceInvite
+	if err := db.DB.Preload("CreatedBy").
+		Where("space_id = ? AND revoked_at IS NULL", spaceID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Where("max_uses = 0 OR uses < max_uses").
+		Order("created_at desc").
+		Find(&invites).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch invites"})
+	}
+
+	response := make([]InviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		response = append(response, InviteResponse{SpaceInvite: invite, URL: inviteURL(invite.Code)})
+	}
+
+	return c.JSON(response)
+}
+
+// Revoke Invite (admins only)
+func RevokeInvite(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	inviteID, err := uuid.Parse(c.Params("inviteId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid invite ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageMembers); err != nil {
+		return authorizeError(c, err, "Only admins can revoke invites")
+	}
+
+	result := db.DB.Model(&models.SpaceInvite{}).
+		Where("id = ? AND space_id = ? AND revoked_at IS NULL", inviteID, spaceID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not revoke invite"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invite not found"})
+	}
+
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditInviteRevoked, "invite", &inviteID, nil, nil)
+
+	return c.JSON(fiber.Map{"message": "Invite revoked successfully"})
+}
+
+// Preview Invite (what a user is about to join)
+func PreviewInvite(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var invite models.SpaceInvite
+	if err := db.DB.Preload("Space").Preload("Space.Owner").Preload("CreatedBy").
+		Where("code = ?", c.Params("code")).First(&invite).Error; err != nil || !invite.IsUsable() || invite.Space.ID == uuid.Nil || invite.Space.ArchivedAt != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invite is invalid or has expired"})
+	}
+	if invite.InviteeID != nil && *invite.InviteeID != userID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invite is invalid or has expired"})
+	}
+
+	var memberCount int64
+	db.DB.Model(&models.SpaceMember{}).Where("space_id = ?", invite.SpaceID).Count(&memberCount)
+
+	var alreadyMember int64
+	db.DB.Model(&models.SpaceMember{}).Where("space_id = ? AND user_id = ?", invite.SpaceID, userID).Count(&alreadyMember)
+
+	return c.JSON(fiber.Map{
+		"space": fiber.Map{
+			"id":           invite.Space.ID,
+			"name":         invite.Space.Name,
+			"owner":        invite.Space.Owner.Username,
+			"member_count": memberCount,
+		},
+		"invited_by":     invite.CreatedBy.Username,
+		"role":           invite.Role,
+		"expires_at":     invite.ExpiresAt,
+		"already_member": alreadyMember > 0,
+	})
+}
+
+// Get My Invites (pending invites addressed to the current user)
+func GetMyInvites(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var invites []models.SpaceInvite
+	if err := db.DB.
+		Joins("JOIN spaces ON spaces.id = space_invites.space_id AND spaces.deleted_at IS NULL AND spaces.archived_at IS NULL").
+		Where("space_invites.invitee_id = ? AND space_invites.revoked_at IS NULL", userID).
+		Where("space_invites.space_id NOT IN (?)", db.DB.Model(&models.SpaceMember{}).Select("space_id").Where("user_id = ?", userID)).
+		Preload("CreatedBy").
+		Order("space_invites.created_at desc").
+		Find(&invites).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch invites"})
+	}
+
+	response := []InviteResponse{}
+	for _, invite := range invites {
+		if invite.IsUsable() {
+			response = append(response, InviteResponse{SpaceInvite: invite, URL: inviteURL(invite.Code)})
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// Redeem Invite (join the space)
+func RedeemInvite(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	errInvalidInvite := errors.New("invalid invite")
+	var member models.SpaceMember
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the invite so concurrent redemptions can't exceed max uses
+		var invite models.SpaceInvite
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code = ?", c.Params("code")).First(&invite).Error; err != nil || !invite.IsUsable() {
+			return errInvalidInvite
+		}
+		if invite.InviteeID != nil && *invite.InviteeID != userID {
+			return errInvalidInvite
+		}
+
+		var space models.Space
+		if err := tx.First(&space, invite.SpaceID).Error; err != nil || space.ArchivedAt != nil {
+			return errInvalidInvite
+		}
+
+		member, err = addSpaceMember(tx, invite.SpaceID, userID, invite.Role)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&invite).Update("uses", gorm.Expr("uses + 1")).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, invite.SpaceID, &userID, models.AuditMemberJoined, "user", &userID, nil,
+			map[string]interface{}{"role": member.Role, "invite_id": invite.ID})
+	})
+	if errors.Is(err, errInvalidInvite) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invite is invalid or has expired"})
+	}
+	if errors.Is(err, errAlreadyMember) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You are already a member of this space"})
+	}
+	var quotaErr *entitlements.QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not join space"})
+	}
+	leaderboard.Invalidate(member.SpaceID)
+
+	return c.JSON(fiber.Map{"message": "Joined space successfully", "member": member})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/leaderboard"
+	"pomodoro-habit-backend/internal/permissions"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Get Space Leaderboard (?period=day, week or month)
+func GetSpaceLeaderboard(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	board, err := leaderboard.Get(spaceID, c.Query("period", leaderboard.PeriodWeek))
+	if errors.Is(err, leaderboard.ErrInvalidPeriod) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Period must be 'day', 'week' or 'month'"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch leaderboard"})
+	}
+
+	return c.JSON(board)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/utils"
+	"strings"
+	"time"
+
+	jwtware "github.com/gofiber/contrib/jwt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// authenticate accepts either a JWT access token bound to an active session
+// or a personal access token.
+func authenticate() fiber.Handler {
+	jwtAuth := jwtware.New(jwtware.Config{
+		KeyFunc:        utils.JWTKeyfunc,
+		SuccessHandler: requireActiveSession,
+	})
+
+	return func(c *fiber.Ctx) error {
+		if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok && strings.HasPrefix(token, patPrefix) {
+			return authenticateAccessToken(c, token)
+		}
+		return jwtAuth(c)
+	}
+}
+
+// requireActiveSession runs after jwtware has verified the signature and
+// rejects access tokens whose session was revoked or has expired.
+func requireActiveSession(c *fiber.Ctx) error {
+	userToken, ok := c.Locals("user").(*jwt.Token)
+	if !ok || userToken == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	claims, err := utils.AccessClaimsFromToken(userToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	var session models.Session
+	if err := db.DB.First(&session, "id = ? AND user_id = ?", claims.SessionID, claims.UserID).Error; err != nil || !session.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session has expired or been revoked"})
+	}
+
+	// Track activity for the device list without writing on every request
+	if time.Since(session.LastSeenAt) > time.Minute {
+		db.DB.Model(&session).UpdateColumns(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"ip_address":   c.IP(),
+		})
+	}
+
+	c.Locals("user_id", session.UserID)
+	c.Locals("session_id", session.ID)
+	return c.Next()
+}
+
+// getUserID returns the user the request was authenticated as.
+func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok || userID == uuid.Nil {
+		return uuid.Nil, fiber.ErrUnauthorized
+	}
+	return userID, nil
+}
+
+// getSessionID returns the session the current access token belongs to.
+func getSessionID(c *fiber.Ctx) (uuid.UUID, error) {
+	sessionID, ok := c.Locals("session_id").(uuid.UUID)
+	if !ok {
+		return uuid.Nil, fiber.ErrUnauthorized
+	}
+	return sessionID, nil
+}
+
+// requireAdmin only lets site admins through. It must run after authenticate.
+func requireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := getUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var user models.User
+		if err := db.DB.Select("id", "is_admin").First(&user, userID).Error; err != nil || !user.IsAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Admin access required"})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/oidc"
+	"pomodoro-habit-backend/internal/utils"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+// The state also goes into a cookie, so a callback only completes in the
+// browser that started the login. Without it an attacker could finish their
+// own login in someone else's browser.
+const (
+	oauthStateCookie     = "oidc_state"
+	oauthStateCookiePath = "/api/v1/auth/oidc"
+)
+
+func setOAuthStateCookie(c *fiber.Ctx, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     oauthStateCookiePath,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		// Lax still sends it on the provider's redirect back to us
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+var (
+	usernameDisallowedChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+	errUnverifiedAccount    = errors.New("account email is not verified")
+)
+
+// OIDC Login (starts the authorization-code flow with PKCE). The response
+// sets the state cookie, so clients that fetch the URL as JSON must send
+// credentials for the callback to succeed.
+func OIDCLogin(c *fiber.Ctx) error {
+	provider, err := oidc.Get(c.Params("provider"))
+	if errors.Is(err, oidc.ErrUnknownProvider) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown identity provider"})
+	}
+	if err != nil {
+		log.Printf("OIDC: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider is unavailable"})
+	}
+
+	state, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+	nonce, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+	codeVerifier, err := utils.GenerateOpaqueToken(48)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+
+	// Clean up abandoned attempts while we're here
+	db.DB.Where("expires_at < ?", time.Now()).Delete(&models.OAuthState{})
+
+	oauthState := models.OAuthState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if err := db.DB.Create(&oauthState).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start login"})
+	}
+
+	setOAuthStateCookie(c, state, oauthState.ExpiresAt)
+
+	authURL := provider.AuthCodeURL(state, nonce, oidc.CodeChallenge(codeVerifier))
+	if c.QueryBool("redirect") {
+		return c.Redirect(authURL, fiber.StatusFound)
+	}
+	return c.JSON(fiber.Map{"authorization_url": authURL, "state": state})
+}
+
+// OIDC Callback (code and state as query params or JSON body)
+func OIDCCallback(c *fiber.Ctx) error {
+	var req struct {
+		Code       string `json:"code"`
+		State      string `json:"state"`
+		DeviceName string `json:"device_name"`
+	}
+	if c.Method() == fiber.MethodPost {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	} else {
+		if errParam := c.Query("error"); errParam != "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Login was cancelled or denied: " + errParam})
+		}
+		req.Code = c.Query("code")
+		req.State = c.Query("state")
+	}
+	if req.Code == "" || req.State == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing code or state"})
+	}
+
+	bound := c.Cookies(oauthStateCookie)
+	setOAuthStateCookie(c, "", time.Unix(0, 0))
+	if bound == "" || subtle.ConstantTimeCompare([]byte(bound), []byte(req.State)) != 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Login was not started in this browser"})
+	}
+
+	provider, err := oidc.Get(c.Params("provider"))
+	if errors.Is(err, oidc.ErrUnknownProvider) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown identity provider"})
+	}
+	if err != nil {
+		log.Printf("OIDC: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider is unavailable"})
+	}
+
+	// States are single use: delete and read back in one statement
+	var oauthState models.OAuthState
+	result := db.DB.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND provider = ? AND expires_at > ?", utils.HashToken(req.State), provider.Name, time.Now()).
+		Delete(&oauthState)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired login state"})
+	}
+
+	rawIDToken, err := provider.Exchange(c.Context(), req.Code, oauthState.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC: code exchange with %s failed: %v", provider.Name, err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Could not complete login with identity provider"})
+	}
+
+	identity, err := provider.VerifyIDToken(rawIDToken, oauthState.Nonce)
+	if err != nil {
+		log.Printf("OIDC: %s: %v", provider.Name, err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Could not verify identity"})
+	}
+
+	user, status, errMsg := resolveOIDCUser(provider.Name, identity)
+	if errMsg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": errMsg})
+	}
+
+	return completeLogin(c, user, req.DeviceName)
+}
+
+// resolveOIDCUser finds the user linked to the external identity, links an
+// existing account with the same verified email, or creates a new account.
+func resolveOIDCUser(provider string, identity *oidc.Identity) (models.User, int, string) {
+	var user models.User
+
+	var link models.UserIdentity
+	if result := db.DB.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&link); result.RowsAffected > 0 {
+		if err := db.DB.First(&user, link.UserID).Error; err != nil {
+			return user, fiber.StatusUnauthorized, "Linked account no longer exists"
+		}
+		return user, 0, ""
+	}
+
+	// Linking by email is only safe when the provider vouches for it
+	if identity.Email == "" || !identity.EmailVerified {
+		return user, fiber.StatusForbidden, "Your identity provider did not share a verified email address"
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Where("LOWER(email) = LOWER(?)", identity.Email).First(&user); result.RowsAffected > 0 {
+			// Someone could have registered this address without owning it
+			if !user.EmailVerified {
+				return errUnverifiedAccount
+			}
+		} else {
+			username, err := uniqueUsername(tx, identity)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			user = models.User{
+				Username:        username,
+				Email:           identity.Email,
+				EmailVerified:   true,
+				EmailVerifiedAt: &now,
+				FirstName:       identity.GivenName,
+				LastName:        identity.FamilyName,
+				AvatarURL:       identity.Picture,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if errors.Is(err, errUnverifiedAccount) {
+		return user, fiber.StatusConflict, "An unverified account with this email already exists. Sign in with your password and verify your email first"
+	}
+	if err != nil {
+		return user, fiber.StatusInternalServerError, "Could not sign in with identity provider"
+	}
+	return user, 0, ""
+}
+
+// uniqueUsername derives a valid username from the identity and appends a
+// random suffix when it is already taken.
+func uniqueUsername(tx *gorm.DB, identity *oidc.Identity) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = usernameDisallowedChars.ReplaceAllString(base, "_")
+	base = strings.Trim(base, "_")
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 24 {
+		base = base[:24]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		tx.Model(&models.User{}).Unscoped().Where("username = ?", candidate).Count(&count)
+		if count == 0 {
+			return candidate, nil
+		}
+		suffix, err := utils.GenerateOpaqueToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + usernameDisallowedChars.ReplaceAllString(suffix, "")
+	}
+	return "", errors.New("could not find a free username")
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/v1/auth/oidc/:provider/callback", OIDCCallback)
+
+	tests := []struct {
+		name   string
+		cookie string
+		status int
+	}{
+		{"no cookie", "", fiber.StatusBadRequest},
+		{"cookie of another login", "other-state", fiber.StatusBadRequest},
+		// Gets past the check and fails on the provider, which isn't configured
+		{"cookie of this login", "attacker-state", fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/auth/oidc/mock/callback?code=code&state=attacker-state", nil)
+			if tt.cookie != "" {
+				req.Header.Set("Cookie", oauthStateCookie+"="+tt.cookie)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
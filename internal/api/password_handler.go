@@ -0,0 +1,190 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/bruteforce"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/mailer"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const passwordResetTTL = time.Hour
+
+const passwordRequirementsError = "Password must be at least 8 characters long and contain at least one uppercase letter, one lowercase letter, and one special character"
+
+// Forgot Password (mails a single-use reset token)
+func ForgotPassword(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+
+	// Every request counts, so the endpoint can't be used to flood an inbox
+	accountKey := bruteforce.MailKey(bruteforce.AccountKey(req.Email))
+	ipKey := bruteforce.MailKey(bruteforce.IPKey(c.IP()))
+	if retryAfter := bruteforce.RetryAfter(c.Context(), accountKey, ipKey); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+	bruteforce.Fail(c.Context(), bruteforce.MailPolicy, accountKey)
+	bruteforce.Fail(c.Context(), bruteforce.MailPolicy, ipKey)
+
+	response := fiber.Map{"message": "If an account with that email exists, a password reset link has been sent"}
+
+	var user models.User
+	if result := db.DB.Where("email = ?", req.Email).First(&user); result.RowsAffected == 0 {
+		return c.JSON(response)
+	}
+
+	token, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate reset token"})
+	}
+
+	tx := db.DB.Begin()
+
+	// Only the most recent reset link works
+	if err := tx.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", time.Now()).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create reset token"})
+	}
+
+	resetToken := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	if err := tx.Create(&resetToken).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create reset token"})
+	}
+
+	tx.Commit()
+
+	cfg := config.LoadConfig()
+	link := strings.TrimRight(cfg.FrontendURL, "/") + "/reset-password?token=" + token
+	mailer.SendAsync(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your PomoHub password",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Someone asked to reset the password of your PomoHub account. Open the link below to choose a new one:\n\n" +
+			link + "\n\n" +
+			"The link expires in 1 hour and can only be used once. If you didn't ask for this you can ignore this email.\n",
+	})
+
+	return c.JSON(response)
+}
+
+// Reset Password (with a token from Forgot Password)
+func ResetPassword(c *fiber.Ctx) error {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if !validatePassword(req.Password) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": passwordRequirementsError})
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+	}
+
+	tx := db.DB.Begin()
+
+	// Claim the token atomically so it can't be used twice
+	now := time.Now()
+	var resetToken models.PasswordResetToken
+	result := tx.Model(&resetToken).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(req.Token), now).
+		Update("used_at", now)
+	if result.Error != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset password"})
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+
+	// Following the emailed link also proves ownership of the address
+	if err := tx.Model(&models.User{}).Where("id = ?", resetToken.UserID).Updates(map[string]interface{}{
+		"password_hash":     hashedPassword,
+		"email_verified":    true,
+		"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", now),
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset password"})
+	}
+
+	tx.Commit()
+
+	// Whoever knew the old password must not stay signed in
+	if err := revokeSessions(resetToken.UserID, uuid.Nil); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Password changed, but could not sign out existing sessions"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Password reset successfully"})
+}
+
+// Change Password (authenticated)
+func ChangePassword(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	sessionID, _ := getSessionID(c)
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if !utils.CheckPasswordHash(req.CurrentPassword, user.PasswordHash) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Current password is incorrect"})
+	}
+
+	if !validatePassword(req.NewPassword) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": passwordRequirementsError})
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+	}
+
+	if err := db.DB.Model(&user).Update("password_hash", hashedPassword).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not change password"})
+	}
+
+	// Keep this device signed in, sign out everywhere else
+	if err := revokeSessions(userID, sessionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Password changed, but could not sign out other sessions"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Password changed successfully"})
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// quotaExceeded responds to an action that would go over a plan limit.
+func quotaExceeded(c *fiber.Ctx, err *entitlements.QuotaError) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": err.Error(),
+		"quota": err.Quota,
+		"limit": err.Limit,
+	})
+}
+
+// Get My Plan (limits and current usage)
+func GetMyPlan(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	plan, err := entitlements.PlanFor(db.DB, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch plan"})
+	}
+
+	var user models.User
+	if err := db.DB.Select("id", "is_admin").First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var ownedSpaces int64
+	db.DB.Model(&models.Space{}).Where("owner_id = ?", userID).Count(&ownedSpaces)
+
+	return c.JSON(fiber.Map{
+		"plan":     plan,
+		"is_admin": user.IsAdmin,
+		"usage": fiber.Map{
+			"owned_spaces": ownedSpaces,
+		},
+	})
+}
+
+// Get Plans (admin)
+func GetPlans(c *fiber.Ctx) error {
+	var plans []models.Plan
+	if err := db.DB.Order("name").Find(&plans).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch plans"})
+	}
+	return c.JSON(plans)
+}
+
+type PlanRequest struct {
+	Name                 string `json:"name"`
+	DisplayName          string `json:"display_name"`
+	MaxOwnedSpaces       *int   `json:"max_owned_spaces"`
+	MaxMembersPerSpace   *int   `json:"max_members_per_space"`
+	MessageRetentionDays *int   `json:"message_retention_days"`
+}
+
+// apply copies the provided limits onto the plan, rejecting negative values.
+func (r PlanRequest) apply(plan *models.Plan) bool {
+	if r.DisplayName != "" {
+		plan.DisplayName = r.DisplayName
+	}
+	if r.MaxOwnedSpaces != nil {
+		plan.MaxOwnedSpaces = *r.MaxOwnedSpaces
+	}
+	if r.MaxMembersPerSpace != nil {
+		plan.MaxMembersPerSpace = *r.MaxMembersPerSpace
+	}
+	if r.MessageRetentionDays != nil {
+		plan.MessageRetentionDays = *r.MessageRetentionDays
+	}
+	return plan.MaxOwnedSpaces >= 0 && plan.MaxMembersPerSpace >= 0 && plan.MessageRetentionDays >= 0
+}
+
+// Create Plan (admin)
+func CreatePlan(c *fiber.Ctx) error {
+	var req PlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	plan := models.Plan{Name: strings.ToLower(strings.TrimSpace(req.Name))}
+	if plan.Name == "" || req.DisplayName == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name and display name are required"})
+	}
+	if !req.apply(&plan) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Limits cannot be negative, use 0 for unlimited"})
+	}
+
+	var existing int64
+	db.DB.Model(&models.Plan{}).Where("name = ?", plan.Name).Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A plan with this name already exists"})
+	}
+
+	if err := db.DB.Create(&plan).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create plan"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(plan)
+}
+
+// Update Plan (admin)
+func UpdatePlan(c *fiber.Ctx) error {
+	var plan models.Plan
+	if err := db.DB.First(&plan, "name = ?", c.Params("name")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Plan not found"})
+	}
+
+	var req PlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if !req.apply(&plan) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Limits cannot be negative, use 0 for unlimited"})
+	}
+
+	if err := db.DB.Save(&plan).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update plan"})
+	}
+
+	return c.JSON(plan)
+}
+
+// Set User Plan (admin)
+func SetUserPlan(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	type Request struct {
+		Plan string `json:"plan"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var plan models.Plan
+	if err := db.DB.First(&plan, "name = ?", req.Plan).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Plan not found"})
+	}
+
+	// Downgrades keep what the user already has, new limits apply from now on
+	result := db.DB.Model(&models.User{}).Where("id = ?", userID).Update("plan_name", plan.Name)
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update plan"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Plan updated successfully", "user_id": userID, "plan": plan})
+}
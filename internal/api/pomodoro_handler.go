@@ -1,10 +1,26 @@
 package api
 
 import (
+	"math"
+	"pomodoro-habit-backend/internal/challenges"
 	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/leaderboard"
 	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Completed sessions count towards shared leaderboards and challenges, so
+// their length is kept to what a single focus interval can plausibly be
+const (
+	minSessionMinutes = 1
+	maxSessionMinutes = 180
 )
 
 // Save Session
@@ -22,6 +38,10 @@ func SavePomodoroSession(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
+	// Abandoned sessions don't count anywhere and are taken as they come
+	if req.Completed && (req.Duration < minSessionMinutes || req.Duration > maxSessionMinutes) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Duration must be between 1 and 180 minutes"})
+	}
 
 	session := models.PomodoroSession{
 		UserID:    userID,
@@ -29,9 +49,43 @@ func SavePomodoroSession(c *fiber.Ctx) error {
 		Completed: req.Completed,
 	}
 
-	if err := db.DB.Create(&session).Error; err != nil {
+	tx := db.DB.Begin()
+
+	// Completed sessions can't overlap, so nobody finishes more focus time
+	// than has passed. The user's row serializes parallel requests.
+	if session.Completed {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save session"})
+		}
+		now := time.Now()
+		var previous models.PomodoroSession
+		result := tx.Where("user_id = ? AND completed = ? AND created_at > ?", userID, true, now.Add(-time.Duration(req.Duration)*time.Minute)).
+			Order("created_at DESC").Limit(1).Find(&previous)
+		if result.Error != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save session"})
+		}
+		if result.RowsAffected > 0 {
+			tx.Rollback()
+			seconds := int64(math.Ceil(previous.CreatedAt.Add(time.Duration(req.Duration) * time.Minute).Sub(now).Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       "This session overlaps your last completed one",
+				"retry_after": seconds,
+			})
+		}
+	}
+
+	if err := tx.Create(&session).Error; err != nil {
+		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save session"})
 	}
+	tx.Commit()
+	if session.Completed {
+		go leaderboard.Record(session)
+		go challenges.Notify(userID)
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(session)
 }
@@ -50,7 +104,70 @@ func GetPomodoroStats(c *fiber.Ctx) error {
 		Select("COALESCE(SUM(duration), 0)").
 		Scan(&totalMinutes)
 
+	// Minutes focused together with a space
+	var groupMinutes int64
+	db.DB.Model(&models.PomodoroSession{}).
+		Where("user_id = ? AND completed = ? AND space_id IS NOT NULL", userID, true).
+		Select("COALESCE(SUM(duration), 0)").
+		Scan(&groupMinutes)
+
 	return c.JSON(fiber.Map{
 		"total_minutes": totalMinutes,
+		"group_minutes": groupMinutes,
+	})
+}
+
+// Get Space Stats (group focus time recorded by the shared timer)
+func GetSpaceStats(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > 365 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Days must be between 1 and 365"})
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	base := func() *gorm.DB {
+		return db.DB.Model(&models.PomodoroSession{}).
+			Where("pomodoro_sessions.space_id = ? AND pomodoro_sessions.completed = ? AND pomodoro_sessions.created_at >= ?", spaceID, true, since)
+	}
+
+	// Every member present for an interval gets their own session row
+	var totals struct {
+		FocusMinutes int64
+		Intervals    int64
+	}
+	base().Select("COALESCE(SUM(duration), 0) AS focus_minutes, COUNT(DISTINCT created_at) AS intervals").Scan(&totals)
+
+	type MemberStats struct {
+		UserID   uuid.UUID `json:"user_id"`
+		Username string    `json:"username"`
+		Minutes  int64     `json:"minutes"`
+		Sessions int64     `json:"sessions"`
+	}
+	members := []MemberStats{}
+	base().Select("pomodoro_sessions.user_id, users.username, SUM(pomodoro_sessions.duration) AS minutes, COUNT(*) AS sessions").
+		Joins("JOIN users ON users.id = pomodoro_sessions.user_id").
+		Group("pomodoro_sessions.user_id, users.username").
+		Order("minutes desc").
+		Scan(&members)
+
+	return c.JSON(fiber.Map{
+		"days":          days,
+		"focus_minutes": totals.FocusMinutes,
+		"intervals":     totals.Intervals,
+		"members":       members,
 	})
 }
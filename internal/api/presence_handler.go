@@ -0,0 +1,114 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/timer"
+	"pomodoro-habit-backend/internal/ws"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PresenceResponse struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	Username         string     `json:"username"`
+	AvatarURL        string     `json:"avatar_url"`
+	Status           string     `json:"status"`
+	EndsAt           *time.Time `json:"ends_at,omitempty"`
+	RemainingSeconds int        `json:"remaining_seconds"`
+	ConnectedAt      time.Time  `json:"connected_at"`
+	Connections      int        `json:"connections"`
+}
+
+// Get Space Presence
+func GetSpacePresence(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	// Sockets are checked when they connect, so only list those still members
+	var memberIDs []uuid.UUID
+	if err := db.DB.Model(&models.SpaceMember{}).Where("space_id = ?", spaceID).Pluck("user_id", &memberIDs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch presence"})
+	}
+	members := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, id := range memberIDs {
+		members[id] = true
+	}
+	roster := []ws.Presence{}
+	for _, p := range ws.GlobalHub.Roster(spaceID) {
+		if members[p.UserID] {
+			roster = append(roster, p)
+		}
+	}
+
+	state, err := timer.Get(spaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch timer"})
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(roster))
+	for _, p := range roster {
+		userIDs = append(userIDs, p.UserID)
+	}
+	users := make(map[uuid.UUID]models.User)
+	if len(userIDs) > 0 {
+		var found []models.User
+		if err := db.DB.Select("id", "username", "avatar_url").Where("id IN ?", userIDs).Find(&found).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch presence"})
+		}
+		for _, u := range found {
+			users[u.ID] = u
+		}
+	}
+
+	now := time.Now()
+	response := make([]PresenceResponse, 0, len(roster))
+	for _, p := range roster {
+		status, endsAt := p.Status, p.EndsAt
+		// Members who have not said otherwise follow the space's shared timer
+		if status == "" {
+			status, endsAt = ws.StatusIdle, nil
+			if state.Status == timer.StatusRunning {
+				status, endsAt = ws.StatusOnBreak, state.EndsAt
+				if state.Phase == timer.PhaseWork {
+					status = ws.StatusFocusing
+				}
+			}
+		}
+
+		remaining := 0
+		if endsAt != nil && endsAt.After(now) {
+			remaining = int(endsAt.Sub(now).Seconds())
+		}
+
+		response = append(response, PresenceResponse{
+			UserID:           p.UserID,
+			Username:         users[p.UserID].Username,
+			AvatarURL:        users[p.UserID].AvatarURL,
+			Status:           status,
+			EndsAt:           endsAt,
+			RemainingSeconds: remaining,
+			ConnectedAt:      p.ConnectedAt,
+			Connections:      p.Connections,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"online": response,
+		"timer":  state,
+	})
+}
@@ -77,8 +77,10 @@ func SearchUsers(c *fiber.Ctx) error {
 	}
 
 	var users []models.User
-	// Search by username or email (case insensitive)
-	if err := db.DB.Where("username ILIKE ? OR email ILIKE ?", "%"+query+"%", "%"+query+"%").Limit(10).Find(&users).Error; err != nil {
+	// Search by username or email (case insensitive), only among verified accounts
+	if err := db.DB.Where("email_verified = ?", true).
+		Where("username ILIKE ? OR email ILIKE ?", "%"+query+"%", "%"+query+"%").
+		Limit(10).Find(&users).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
 	}
 
@@ -1,13 +1,13 @@
 package api
 
 import (
-	"pomodoro-habit-backend/internal/config"
-
-	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupRoutes(app *fiber.App) {
+	// Public keys for verifying our tokens
+	app.Get("/.well-known/jwks.json", GetJWKS)
+
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
 
@@ -23,26 +23,113 @@ func SetupRoutes(app *fiber.App) {
 	auth := v1.Group("/auth")
 	auth.Post("/register", Register)
 	auth.Post("/login", Login)
+	auth.Post("/2fa", VerifyTwoFactorLogin)
+	auth.Post("/refresh", RefreshToken)
+	auth.Post("/logout", Logout)
+	auth.Get("/verify", VerifyEmail)
+	auth.Post("/verify", VerifyEmail)
+	auth.Post("/resend-verification", ResendVerification)
+	auth.Post("/forgot-password", ForgotPassword)
+	auth.Post("/reset-password", ResetPassword)
+	auth.Get("/oidc/:provider", OIDCLogin)
+	auth.Get("/oidc/:provider/callback", OIDCCallback)
+	auth.Post("/oidc/:provider/callback", OIDCCallback)
+
+	// Space schedules for calendar apps, secured by the token in the URL
+	v1.Get("/calendar/:token", GetCalendarFeed)
 
 	// Protected Routes
-	cfg := config.LoadConfig()
-	v1.Use(jwtware.New(jwtware.Config{
-		SigningKey: jwtware.SigningKey{Key: []byte(cfg.JWTSecret)},
-	}))
+	v1.Use(authenticate())
+
+	auth.Post("/logout-all", LogoutAll)
 
 	// Spaces
 	spaces := v1.Group("/spaces")
 	spaces.Post("/", CreateSpace)
 	spaces.Get("/", GetMySpaces)
+	spaces.Get("/discover", DiscoverSpaces)
+	spaces.Get("/transfers", GetIncomingTransfers)
+	spaces.Post("/transfers/:transferId/accept", AcceptOwnershipTransfer)
+	spaces.Post("/transfers/:transferId/decline", DeclineOwnershipTransfer)
+	spaces.Get("/deleted", GetDeletedSpaces)
+	spaces.Post("/from-template", CreateSpaceFromTemplate)
 	spaces.Get("/:spaceId", GetSpaceDetails)
 	spaces.Put("/:spaceId", UpdateSpace)
 	spaces.Post("/:spaceId/members", AddMember)
 	spaces.Delete("/:spaceId/members/:userId", RemoveMember)
+	spaces.Put("/:spaceId/members/:userId/role", UpdateMemberRole)
+	spaces.Post("/:spaceId/transfer", TransferOwnership)
+	spaces.Delete("/:spaceId/transfer", CancelOwnershipTransfer)
 	spaces.Delete("/:spaceId", DeleteSpace)
+	spaces.Post("/:spaceId/archive", ArchiveSpace)
+	spaces.Delete("/:spaceId/archive", UnarchiveSpace)
+	spaces.Post("/:spaceId/restore", RestoreSpace)
+
+	// Invites
+	spaces.Post("/:spaceId/invites", CreateInvite)
+	spaces.Get("/:spaceId/invites", GetInvites)
+	spaces.Delete("/:spaceId/invites/:inviteId", RevokeInvite)
+
+	invites := v1.Group("/invites")
+	invites.Get("/", GetMyInvites)
+	invites.Get("/:code", PreviewInvite)
+	invites.Post("/:code/redeem", RedeemInvite)
+
+	// Joining
+	spaces.Post("/:spaceId/join", JoinSpace)
+	spaces.Get("/:spaceId/join-requests", GetJoinRequests)
+	spaces.Post("/:spaceId/join-requests/:requestId/approve", ApproveJoinRequest)
+	spaces.Post("/:spaceId/join-requests/:requestId/deny", DenyJoinRequest)
+
+	// Shared Timer
+	spaces.Get("/:spaceId/timer", GetSpaceTimer)
+	spaces.Post("/:spaceId/timer/:action", ControlSpaceTimer)
+	spaces.Get("/:spaceId/stats", GetSpaceStats)
+	spaces.Get("/:spaceId/presence", GetSpacePresence)
+	spaces.Get("/:spaceId/leaderboard", GetSpaceLeaderboard)
+	spaces.Get("/:spaceId/audit", GetSpaceAudit)
+
+	// Task Board
+	spaces.Get("/:spaceId/tasks", GetSpaceTasks)
+	spaces.Post("/:spaceId/tasks", CreateSpaceTask)
+	spaces.Put("/:spaceId/tasks/:taskId", UpdateSpaceTask)
+	spaces.Put("/:spaceId/tasks/:taskId/move", MoveSpaceTask)
+	spaces.Delete("/:spaceId/tasks/:taskId", DeleteSpaceTask)
+	spaces.Get("/:spaceId/tasks/:taskId/comments", GetTaskComments)
+	spaces.Post("/:spaceId/tasks/:taskId/comments", AddTaskComment)
+	spaces.Delete("/:spaceId/tasks/:taskId/comments/:commentId", DeleteTaskComment)
+
+	// Scheduled Sessions
+	spaces.Get("/:spaceId/events", GetSpaceEvents)
+	spaces.Post("/:spaceId/events", CreateSpaceEvent)
+	spaces.Put("/:spaceId/events/:eventId", UpdateSpaceEvent)
+	spaces.Delete("/:spaceId/events/:eventId", DeleteSpaceEvent)
+	spaces.Put("/:spaceId/events/:eventId/rsvp", RSVPSpaceEvent)
+	spaces.Get("/:spaceId/events/:eventId/rsvps", GetSpaceEventRSVPs)
+	spaces.Get("/:spaceId/calendar", GetSpaceCalendar)
+	spaces.Post("/:spaceId/calendar/reset", ResetSpaceCalendar)
+
+	// Challenges
+	spaces.Get("/:spaceId/challenges", GetSpaceChallenges)
+	spaces.Post("/:spaceId/challenges", CreateSpaceChallenge)
+	spaces.Get("/:spaceId/challenges/:challengeId", GetSpaceChallenge)
+	spaces.Delete("/:spaceId/challenges/:challengeId", DeleteSpaceChallenge)
+
+	// Templates
+	spaces.Post("/:spaceId/templates", SaveSpaceTemplate)
+	spaces.Post("/:spaceId/clone", CloneSpace)
+
+	templates := v1.Group("/templates")
+	templates.Get("/", GetTemplates)
+	templates.Delete("/:templateId", DeleteTemplate)
 
 	// Chat
 	spaces.Post("/:spaceId/messages", SendMessage)
 	spaces.Get("/:spaceId/messages", GetMessages)
+	spaces.Get("/:spaceId/messages/pinned", GetPinnedMessages)
+	spaces.Delete("/:spaceId/messages/:messageId", DeleteMessage)
+	spaces.Post("/:spaceId/messages/:messageId/pin", PinMessage)
+	spaces.Delete("/:spaceId/messages/:messageId/pin", UnpinMessage)
 
 	// Productivity
 	// Todos
@@ -67,6 +154,20 @@ func SetupRoutes(app *fiber.App) {
 	// Profile & Posts
 	users := v1.Group("/users")
 	users.Put("/me", UpdateProfile)
+	users.Get("/me/export", ExportMyData)
+	users.Post("/me/deletion", ScheduleAccountDeletion)
+	users.Delete("/me/deletion", CancelAccountDeletion)
+	users.Put("/me/password", ChangePassword)
+	users.Post("/me/2fa/setup", SetupTwoFactor)
+	users.Post("/me/2fa/confirm", ConfirmTwoFactor)
+	users.Post("/me/2fa/disable", DisableTwoFactor)
+	users.Post("/me/2fa/recovery-codes", RegenerateRecoveryCodes)
+	users.Get("/me/tokens", GetAccessTokens)
+	users.Post("/me/tokens", CreateAccessToken)
+	users.Delete("/me/tokens/:tokenId", DeleteAccessToken)
+	users.Get("/me/sessions", GetSessions)
+	users.Delete("/me/sessions/:sessionId", DeleteSession)
+	users.Get("/me/plan", GetMyPlan)
 	users.Get("/search", SearchUsers)
 	users.Get("/:username", GetUserProfile)
 	users.Get("/:username/posts", GetUserPosts)
@@ -76,6 +177,13 @@ func SetupRoutes(app *fiber.App) {
 	posts.Get("/feed", GetFriendsFeed)
 	posts.Delete("/:id", DeletePost)
 
+	// Site Administration
+	admin := v1.Group("/admin", requireAdmin())
+	admin.Get("/plans", GetPlans)
+	admin.Post("/plans", CreatePlan)
+	admin.Put("/plans/:name", UpdatePlan)
+	admin.Put("/users/:userId/plan", SetUserPlan)
+
 	// Friends
 	friends := v1.Group("/friends")
 	friends.Post("/request/:userId", SendFriendRequest)
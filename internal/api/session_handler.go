@@ -0,0 +1,136 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/ws"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// revokeSession revokes a single session and drops its live WebSocket connections.
+func revokeSession(sessionID uuid.UUID) error {
+	if err := db.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	ws.GlobalHub.DisconnectSessions(sessionID)
+	return nil
+}
+
+// revokeSessions revokes every active session of a user, optionally keeping one.
+func revokeSessions(userID uuid.UUID, keep uuid.UUID) error {
+	var sessionIDs []uuid.UUID
+	if err := db.DB.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keep).
+		Pluck("id", &sessionIDs).Error; err != nil {
+		return err
+	}
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	if err := db.DB.Model(&models.Session{}).
+		Where("id IN ?", sessionIDs).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	ws.GlobalHub.DisconnectSessions(sessionIDs...)
+	return nil
+}
+
+// deviceNameFromUserAgent builds a readable label like "Firefox on Windows"
+// for clients that don't send a device name themselves.
+func deviceNameFromUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	platform := "Unknown OS"
+	switch {
+	case strings.Contains(ua, "Android"):
+		platform = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		platform = "iOS"
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "Mac OS"), strings.Contains(ua, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+
+	client := "Unknown client"
+	switch {
+	case strings.Contains(ua, "Tauri"):
+		client = "PomoHub"
+	case strings.Contains(ua, "Edg/"):
+		client = "Edge"
+	case strings.Contains(ua, "Firefox/"):
+		client = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		client = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		client = "Safari"
+	case strings.Contains(ua, "curl/"):
+		client = "curl"
+	}
+
+	return client + " on " + platform
+}
+
+// Get Sessions (active logins of the current user)
+func GetSessions(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	currentSessionID, _ := getSessionID(c)
+
+	var sessions []models.Session
+	if err := db.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch sessions"})
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		response = append(response, SessionResponse{Session: s, Current: s.ID == currentSessionID})
+	}
+
+	return c.JSON(response)
+}
+
+// Delete Session (signs the user out on that device)
+func DeleteSession(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid session ID"})
+	}
+
+	var session models.Session
+	if err := db.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+
+	if err := revokeSession(session.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not revoke session"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Session revoked successfully"})
+}
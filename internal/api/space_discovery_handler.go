@@ -0,0 +1,334 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/leaderboard"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxSpaceTags      = 10
+	maxSpaceTagLength = 32
+	discoverPageSize  = 20
+)
+
+type DiscoverSpaceResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+	Visibility  string    `json:"visibility"`
+	Owner       string    `json:"owner"`
+	MemberCount int64     `json:"member_count"`
+	IsMember    bool      `json:"is_member"`
+	HasPending  bool      `json:"has_pending_request"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// normalizeTags lowercases, trims and de-duplicates topic tags.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxSpaceTagLength {
+			return nil, errors.New("Tags can be at most 32 characters long")
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxSpaceTags {
+		return nil, errors.New("A space can have at most 10 tags")
+	}
+	return normalized, nil
+}
+
+func isValidVisibility(visibility string) bool {
+	switch visibility {
+	case models.SpaceVisibilityPrivate, models.SpaceVisibilityListed, models.SpaceVisibilityOpen:
+		return true
+	}
+	return false
+}
+
+// Discover Spaces (listed and open spaces, searchable by name, description and tag)
+func DiscoverSpaces(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	query := db.DB.Model(&models.Space{}).
+		Preload("Owner").
+		Where("visibility IN ?", []string{models.SpaceVisibilityListed, models.SpaceVisibilityOpen}).
+		Where("archived_at IS NULL")
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+	if tag := strings.ToLower(strings.TrimSpace(c.Query("tag"))); tag != "" {
+		query = query.Where("tags @> jsonb_build_array(?::text)", tag)
+	}
+
+	var spaces []models.Space
+	if err := query.Order("created_at desc").
+		Limit(discoverPageSize).Offset((page - 1) * discoverPageSize).
+		Find(&spaces).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch spaces"})
+	}
+
+	spaceIDs := make([]uuid.UUID, 0, len(spaces))
+	for _, space := range spaces {
+		spaceIDs = append(spaceIDs, space.ID)
+	}
+
+	type countRow struct {
+		SpaceID uuid.UUID
+		Count   int64
+	}
+	var counts []countRow
+	memberCounts := make(map[uuid.UUID]int64)
+	memberOf := make(map[uuid.UUID]bool)
+	pendingFor := make(map[uuid.UUID]bool)
+	if len(spaceIDs) > 0 {
+		db.DB.Model(&models.SpaceMember{}).Select("space_id, count(*) as count").
+			Where("space_id IN ?", spaceIDs).Group("space_id").Scan(&counts)
+		for _, row := range counts {
+			memberCounts[row.SpaceID] = row.Count
+		}
+
+		var joined []uuid.UUID
+		db.DB.Model(&models.SpaceMember{}).Where("space_id IN ? AND user_id = ?", spaceIDs, userID).Pluck("space_id", &joined)
+		for _, id := range joined {
+			memberOf[id] = true
+		}
+
+		var pending []uuid.UUID
+		db.DB.Model(&models.SpaceJoinRequest{}).Where("space_id IN ? AND user_id = ? AND status = ?", spaceIDs, userID, "pending").Pluck("space_id", &pending)
+		for _, id := range pending {
+			pendingFor[id] = true
+		}
+	}
+
+	response := make([]DiscoverSpaceResponse, 0, len(spaces))
+	for _, space := range spaces {
+		response = append(response, DiscoverSpaceResponse{
+			ID:          space.ID,
+			Name:        space.Name,
+			Description: space.Description,
+			Tags:        space.Tags,
+			Visibility:  space.Visibility,
+			Owner:       space.Owner.Username,
+			MemberCount: memberCounts[space.ID],
+			IsMember:    memberOf[space.ID],
+			HasPending:  pendingFor[space.ID],
+			CreatedAt:   space.CreatedAt,
+		})
+	}
+
+	return c.JSON(fiber.Map{"spaces": response, "page": page})
+}
+
+// Join Space (instantly for open spaces, via a join request for listed ones)
+func JoinSpace(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		Message string `json:"message"`
+	}
+	var req Request
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+	}
+	if len(req.Message) > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Message can be at most 500 characters long"})
+	}
+
+	var space models.Space
+	if err := db.DB.First(&space, spaceID).Error; err != nil || space.Visibility == models.SpaceVisibilityPrivate || space.ArchivedAt != nil {
+		// Private spaces are indistinguishable from missing ones
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
+	}
+
+	var existing int64
+	db.DB.Model(&models.SpaceMember{}).Where("space_id = ? AND user_id = ?", spaceID, userID).Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You are already a member of this space"})
+	}
+
+	if space.Visibility == models.SpaceVisibilityOpen {
+		var member models.SpaceMember
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			member, err = addSpaceMember(tx, spaceID, userID, models.SpaceRoleMember)
+			return err
+		})
+		if errors.Is(err, errAlreadyMember) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You are already a member of this space"})
+		}
+		var quotaErr *entitlements.QuotaError
+		if errors.As(err, &quotaErr) {
+			return quotaExceeded(c, quotaErr)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not join space"})
+		}
+		leaderboard.Invalidate(spaceID)
+		models.RecordAudit(db.DB, spaceID, &userID, models.AuditMemberJoined, "user", &userID, nil, map[string]interface{}{"role": member.Role})
+		return c.JSON(fiber.Map{"message": "Joined space successfully", "member": member})
+	}
+
+	var pending int64
+	db.DB.Model(&models.SpaceJoinRequest{}).Where("space_id = ? AND user_id = ? AND status = ?", spaceID, userID, "pending").Count(&pending)
+	if pending > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You already have a pending request for this space"})
+	}
+
+	joinRequest := models.SpaceJoinRequest{
+		SpaceID: spaceID,
+		UserID:  userID,
+		Message: strings.TrimSpace(req.Message),
+		Status:  "pending",
+	}
+	if err := db.DB.Create(&joinRequest).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send join request"})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Join request sent", "request": joinRequest})
+}
+
+// Get Join Requests (pending requests of a space, admins only)
+func GetJoinRequests(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageMembers); err != nil {
+		return authorizeError(c, err, "Only admins can view join requests")
+	}
+
+	var requests []models.SpaceJoinRequest
+	if err := db.DB.Preload("User").
+		Where("space_id = ? AND status = ?", spaceID, "pending").
+		Order("created_at asc").
+		Find(&requests).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch join requests"})
+	}
+
+	return c.JSON(requests)
+}
+
+// Approve Join Request (admins only)
+func ApproveJoinRequest(c *fiber.Ctx) error {
+	return reviewJoinRequest(c, true)
+}
+
+// Deny Join Request (admins only)
+func DenyJoinRequest(c *fiber.Ctx) error {
+	return reviewJoinRequest(c, false)
+}
+
+func reviewJoinRequest(c *fiber.Ctx, approve bool) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	requestID, err := uuid.Parse(c.Params("requestId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageMembers); err != nil {
+		return authorizeError(c, err, "Only admins can review join requests")
+	}
+
+	errRequestNotFound := errors.New("join request not found")
+	var joinRequest models.SpaceJoinRequest
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the request so two admins can't review it at the same time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND space_id = ? AND status = ?", requestID, spaceID, "pending").
+			First(&joinRequest).Error; err != nil {
+			return errRequestNotFound
+		}
+
+		status, action := "denied", models.AuditJoinRequestDenied
+		if approve {
+			status, action = "approved", models.AuditJoinRequestApproved
+			// Someone who joined another way in the meantime is simply approved
+			if _, err := addSpaceMember(tx, spaceID, joinRequest.UserID, models.SpaceRoleMember); err != nil && !errors.Is(err, errAlreadyMember) {
+				return err
+			}
+		}
+
+		now := time.Now()
+		joinRequest.Status = status
+		joinRequest.ReviewedByID = &userID
+		joinRequest.ReviewedAt = &now
+		if err := tx.Model(&joinRequest).Updates(map[string]interface{}{
+			"status":         status,
+			"reviewed_by_id": userID,
+			"reviewed_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, spaceID, &userID, action, "join_request", &joinRequest.ID, nil,
+			map[string]interface{}{"user_id": joinRequest.UserID})
+	})
+	if errors.Is(err, errRequestNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Join request not found"})
+	}
+	var quotaErr *entitlements.QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not review join request"})
+	}
+	if approve {
+		leaderboard.Invalidate(spaceID)
+	}
+
+	return c.JSON(joinRequest)
+}
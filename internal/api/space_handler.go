@@ -1,30 +1,62 @@
 package api
 
 import (
+	"errors"
+	"pomodoro-habit-backend/internal/config"
 	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/leaderboard"
 	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/ws"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// Middleware to extract user_id from JWT
-func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
-	userToken, ok := c.Locals("user").(*jwt.Token)
-	if !ok || userToken == nil {
-		return uuid.Nil, fiber.ErrUnauthorized
+var errAlreadyMember = errors.New("user is already a member of this space")
+
+// addSpaceMember adds a user to a space with the given role. It has to run in
+// a transaction, which holds the member limit's lock on the space. Once it is
+// committed, the caller invalidates the space's leaderboard so it is rebuilt
+// with the member's earlier sessions.
+func addSpaceMember(tx *gorm.DB, spaceID, userID uuid.UUID, role string) (models.SpaceMember, error) {
+	var existing models.SpaceMember
+	if result := tx.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&existing); result.RowsAffected > 0 {
+		return existing, errAlreadyMember
+	}
+	if err := entitlements.CheckMembers(tx, spaceID); err != nil {
+		return models.SpaceMember{}, err
 	}
-	claims, ok := userToken.Claims.(jwt.MapClaims)
-	if !ok {
-		return uuid.Nil, fiber.ErrUnauthorized
+
+	member := models.SpaceMember{
+		SpaceID:  spaceID,
+		UserID:   userID,
+		Role:     role,
+		JoinedAt: time.Now(),
 	}
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		return uuid.Nil, fiber.ErrUnauthorized
+	if err := tx.Create(&member).Error; err != nil {
+		return member, err
+	}
+	return member, nil
+}
+
+// authorizeError turns a failed permissions.Authorize check into a response.
+func authorizeError(c *fiber.Ctx, err error, forbidden string) error {
+	switch {
+	case errors.Is(err, permissions.ErrSpaceNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
+	case errors.Is(err, permissions.ErrSpaceArchived):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "This space is archived and read-only"})
+	case errors.Is(err, permissions.ErrNotMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this space"})
+	case errors.Is(err, permissions.ErrForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": forbidden})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not check permissions"})
 	}
-	return uuid.Parse(userIDStr)
 }
 
 // Create Space
@@ -46,13 +78,6 @@ func CreateSpace(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Space name is required"})
 	}
 
-	// Check Space Limit (Max 3 owned spaces)
-	var count int64
-	db.DB.Model(&models.Space{}).Where("owner_id = ?", userID).Count(&count)
-	if count >= 3 {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only create up to 3 spaces"})
-	}
-
 	space := models.Space{
 		Name:    req.Name,
 		OwnerID: userID,
@@ -60,16 +85,27 @@ func CreateSpace(c *fiber.Ctx) error {
 
 	tx := db.DB.Begin()
 
+	// Check Space Limit of the user's plan, in the transaction so parallel
+	// requests can't both pass it
+	if err := entitlements.CheckOwnedSpaces(tx, userID); err != nil {
+		tx.Rollback()
+		var quotaErr *entitlements.QuotaError
+		if errors.As(err, &quotaErr) {
+			return quotaExceeded(c, quotaErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create space"})
+	}
+
 	if err := tx.Create(&space).Error; err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create space"})
 	}
 
-	// Add owner as a member (admin)
+	// Add owner as a member
 	member := models.SpaceMember{
 		SpaceID:  space.ID,
 		UserID:   userID,
-		Role:     "admin",
+		Role:     models.SpaceRoleOwner,
 		JoinedAt: time.Now(),
 	}
 
@@ -91,9 +127,15 @@ func GetMySpaces(c *fiber.Ctx) error {
 	}
 
 	var spaces []models.Space
-	// Find spaces where user is a member
-	err = db.DB.Joins("JOIN space_members ON space_members.space_id = spaces.id").
-		Where("space_members.user_id = ?", userID).
+	// Find spaces where user is a member, archived ones only on request
+	query := db.DB.Joins("JOIN space_members ON space_members.space_id = spaces.id").
+		Where("space_members.user_id = ?", userID)
+	if c.QueryBool("archived") {
+		query = query.Where("spaces.archived_at IS NOT NULL")
+	} else {
+		query = query.Where("spaces.archived_at IS NULL")
+	}
+	err = query.
 		Preload("Members").
 		Preload("Members.User"). // Load user details for members
 		Find(&spaces).Error
@@ -120,9 +162,8 @@ func GetSpaceDetails(c *fiber.Ctx) error {
 
 	var space models.Space
 	// Check if user is a member
-	var membership models.SpaceMember
-	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&membership).Error; err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this space"})
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
 	}
 
 	if err := db.DB.Preload("Members").Preload("Members.User").First(&space, spaceID).Error; err != nil {
@@ -153,13 +194,9 @@ func AddMember(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	// Check if current user is admin/owner of the space
-	var membership models.SpaceMember
-	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, currentUserID).First(&membership).Error; err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this space"})
-	}
-	if membership.Role != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admins can add members"})
+	// Check if current user may manage members of the space
+	if _, err := permissions.Authorize(spaceID, currentUserID, permissions.ManageMembers); err != nil {
+		return authorizeError(c, err, "Only admins can add members")
 	}
 
 	// Check if target user exists
@@ -168,23 +205,25 @@ func AddMember(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
 	}
 
-	// Check if already a member
-	var existingMember models.SpaceMember
-	if result := db.DB.Where("space_id = ? AND user_id = ?", spaceID, req.UserID).First(&existingMember); result.RowsAffected > 0 {
+	// Add Member
+	var newMember models.SpaceMember
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		newMember, err = addSpaceMember(tx, spaceID, req.UserID, models.SpaceRoleMember)
+		return err
+	})
+	if errors.Is(err, errAlreadyMember) {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "User is already a member"})
 	}
-
-	// Add Member
-	newMember := models.SpaceMember{
-		SpaceID:  spaceID,
-		UserID:   req.UserID,
-		Role:     "member",
-		JoinedAt: time.Now(),
+	var quotaErr *entitlements.QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaExceeded(c, quotaErr)
 	}
-
-	if err := db.DB.Create(&newMember).Error; err != nil {
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not add member"})
 	}
+	leaderboard.Invalidate(spaceID)
+
+	models.RecordAudit(db.DB, spaceID, &currentUserID, models.AuditMemberAdded, "user", &req.UserID, nil, map[string]interface{}{"role": newMember.Role})
 
 	return c.JSON(fiber.Map{"message": "Member added successfully", "member": newMember})
 }
@@ -203,23 +242,107 @@ func RemoveMember(c *fiber.Ctx) error {
 	targetUserID, _ := uuid.Parse(userIDStr)
 
 	// Check permissions
-	var currentUserMembership models.SpaceMember
-	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, currentUserID).First(&currentUserMembership).Error; err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
-	}
-
-	// Allow if removing self OR if admin removing someone else
-	if currentUserID != targetUserID && currentUserMembership.Role != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admins can remove other members"})
+	currentUserMembership, err := permissions.Authorize(spaceID, currentUserID, permissions.ViewSpace)
+	if err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	action, removedRole := models.AuditMemberLeft, currentUserMembership.Role
+	if currentUserID == targetUserID {
+		// Anyone may leave, except the owner who would orphan the space
+		if currentUserMembership.Role == models.SpaceRoleOwner {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "The owner cannot leave the space, transfer ownership or delete it instead"})
+		}
+	} else {
+		// Removing someone else needs the permission and a higher role than theirs
+		if !permissions.Can(currentUserMembership.Role, permissions.ManageMembers) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admins can remove other members"})
+		}
+		var targetMembership models.SpaceMember
+		if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, targetUserID).First(&targetMembership).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+		}
+		if !permissions.Outranks(currentUserMembership.Role, targetMembership.Role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot remove a member with an equal or higher role"})
+		}
+		action, removedRole = models.AuditMemberRemoved, targetMembership.Role
 	}
 
 	if err := db.DB.Delete(&models.SpaceMember{}, "space_id = ? AND user_id = ?", spaceID, targetUserID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not remove member"})
 	}
 
+	// Their tasks go back to being unassigned
+	db.DB.Model(&models.SpaceTask{}).Where("space_id = ? AND assignee_id = ?", spaceID, targetUserID).Update("assignee_id", nil)
+
+	// Their RSVPs go, the sessions they scheduled stay on the calendar under the owner
+	db.DB.Where("space_id = ? AND user_id = ?", spaceID, targetUserID).Delete(&models.SpaceEventRSVP{})
+	db.DB.Unscoped().Model(&models.SpaceEvent{}).Where("space_id = ? AND created_by_id = ?", spaceID, targetUserID).
+		Update("created_by_id", gorm.Expr("(SELECT owner_id FROM spaces WHERE spaces.id = ?)", spaceID))
+
+	models.RecordAudit(db.DB, spaceID, &currentUserID, action, "user", &targetUserID, map[string]interface{}{"role": removedRole}, nil)
+
+	// Open sockets were only checked when they connected
+	ws.GlobalHub.DisconnectMember(spaceID, targetUserID)
+
 	return c.JSON(fiber.Map{"message": "Member removed successfully"})
 }
 
+// Update Member Role
+func UpdateMemberRole(c *fiber.Ctx) error {
+	currentUserID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	targetUserID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	type Request struct {
+		Role string `json:"role"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if !permissions.IsValidRole(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Role must be 'admin', 'moderator', 'member' or 'guest'"})
+	}
+	if req.Role == models.SpaceRoleOwner {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Ownership cannot be assigned by changing roles"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, currentUserID, permissions.ManageMembers)
+	if err != nil {
+		return authorizeError(c, err, "Only admins can change roles")
+	}
+
+	var target models.SpaceMember
+	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, targetUserID).First(&target).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+	}
+
+	// Members can only be moved around below the acting member's own role
+	if !permissions.Outranks(membership.Role, target.Role) || !permissions.Outranks(membership.Role, req.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only assign roles below your own"})
+	}
+
+	if err := db.DB.Model(&target).Where("space_id = ? AND user_id = ?", spaceID, targetUserID).Update("role", req.Role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update role"})
+	}
+	models.RecordAudit(db.DB, spaceID, &currentUserID, models.AuditMemberRoleChanged, "user", &targetUserID,
+		map[string]interface{}{"role": target.Role}, map[string]interface{}{"role": req.Role})
+	target.Role = req.Role
+
+	return c.JSON(target)
+}
+
 // Delete Space
 func DeleteSpace(c *fiber.Ctx) error {
 	currentUserID, err := getUserID(c)
@@ -235,24 +358,33 @@ func DeleteSpace(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
 	}
 
-	if space.OwnerID != currentUserID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the owner can delete the space"})
+	if _, err := permissions.Authorize(spaceID, currentUserID, permissions.DeleteSpace); err != nil {
+		return authorizeError(c, err, "Only the owner can delete the space")
 	}
 
-	// Delete space (Cascade delete should handle members/messages if configured in DB, but GORM soft delete might need manual handling or constraint setup. For now, simple delete)
-	// Ideally we should delete members first or rely on DB FK constraints ON DELETE CASCADE
+	var memberIDs []uuid.UUID
+	db.DB.Model(&models.SpaceMember{}).Where("space_id = ?", spaceID).Pluck("user_id", &memberIDs)
+
+	// Soft delete only: members, messages and the rest stay until the restore
+	// window is over and the purge job removes everything for good
 
 	// Transaction
 	tx := db.DB.Begin()
-	if err := tx.Delete(&models.SpaceMember{}, "space_id = ?", spaceID).Error; err != nil {
+	if err := tx.Delete(&space).Error; err != nil {
 		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete members"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete space"})
 	}
-	if err := tx.Delete(&space).Error; err != nil {
+	if err := models.RecordAudit(tx, spaceID, &currentUserID, models.AuditSpaceDeleted, "space", &spaceID, map[string]interface{}{"name": space.Name}, nil); err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete space"})
 	}
 	tx.Commit()
 
-	return c.JSON(fiber.Map{"message": "Space deleted successfully"})
+	ws.GlobalHub.DisconnectMember(spaceID, memberIDs...)
+
+	cfg := config.LoadConfig()
+	return c.JSON(fiber.Map{
+		"message":          "Space deleted successfully",
+		"restorable_until": time.Now().Add(cfg.SpaceRestoreWindow),
+	})
 }
@@ -3,6 +3,9 @@ package api
 import (
 	"pomodoro-habit-backend/internal/db"
 	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"reflect"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -27,6 +30,10 @@ func UpdateSpace(c *fiber.Ctx) error {
 		PomodoroShortBreakDuration int    `json:"pomodoro_short_break_duration"`
 		PomodoroLongBreakDuration  int    `json:"pomodoro_long_break_duration"`
 		PomodoroRounds             int    `json:"pomodoro_rounds"`
+
+		Visibility  string    `json:"visibility"`
+		Description *string   `json:"description"`
+		Tags        *[]string `json:"tags"`
 	}
 	var req Request
 	if err := c.BodyParser(&req); err != nil {
@@ -38,15 +45,12 @@ func UpdateSpace(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
 	}
 
-	// Only owner or admin can update settings? Let's say Admin.
-	var membership models.SpaceMember
-	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&membership).Error; err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this space"})
-	}
-	if membership.Role != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admins can update space settings"})
+	if _, err := permissions.Authorize(spaceID, userID, permissions.EditSettings); err != nil {
+		return authorizeError(c, err, "Only admins can update space settings")
 	}
 
+	previous := space
+
 	// Update fields if provided
 	if req.Name != "" {
 		space.Name = req.Name
@@ -63,10 +67,58 @@ func UpdateSpace(c *fiber.Ctx) error {
 	if req.PomodoroRounds > 0 {
 		space.PomodoroRounds = req.PomodoroRounds
 	}
+	if req.Visibility != "" {
+		if !isValidVisibility(req.Visibility) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Visibility must be 'private', 'listed' or 'open'"})
+		}
+		space.Visibility = req.Visibility
+	}
+	if req.Description != nil {
+		if len(*req.Description) > 1000 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Description can be at most 1000 characters long"})
+		}
+		space.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		space.Tags = tags
+	}
 
 	if err := db.DB.Save(&space).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update space"})
 	}
 
+	if before, after := settingsDiff(previous, space); len(after) > 0 {
+		models.RecordAudit(db.DB, spaceID, &userID, models.AuditSettingsUpdated, "space", &spaceID, before, after)
+	}
+
 	return c.JSON(space)
 }
+
+// settingsDiff returns the old and new values of the space settings that
+// changed.
+func settingsDiff(previous, current models.Space) (map[string]interface{}, map[string]interface{}) {
+	before := map[string]interface{}{}
+	after := map[string]interface{}{}
+	compare := func(field string, old, new interface{}) {
+		if !reflect.DeepEqual(old, new) {
+			before[field] = old
+			after[field] = new
+		}
+	}
+
+	compare("name", previous.Name, current.Name)
+	compare("pomodoro_work_duration", previous.PomodoroWorkDuration, current.PomodoroWorkDuration)
+	compare("pomodoro_short_break_duration", previous.PomodoroShortBreakDuration, current.PomodoroShortBreakDuration)
+	compare("pomodoro_long_break_duration", previous.PomodoroLongBreakDuration, current.PomodoroLongBreakDuration)
+	compare("pomodoro_rounds", previous.PomodoroRounds, current.PomodoroRounds)
+	compare("visibility", previous.Visibility, current.Visibility)
+	compare("description", previous.Description, current.Description)
+	if len(previous.Tags) > 0 || len(current.Tags) > 0 {
+		compare("tags", previous.Tags, current.Tags)
+	}
+	return before, after
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/timer"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Archive Space (owner only, makes it read-only)
+func ArchiveSpace(c *fiber.Ctx) error {
+	return setSpaceArchived(c, true)
+}
+
+// Unarchive Space (owner only)
+func UnarchiveSpace(c *fiber.Ctx) error {
+	return setSpaceArchived(c, false)
+}
+
+func setSpaceArchived(c *fiber.Ctx, archived bool) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ArchiveSpace); err != nil {
+		return authorizeError(c, err, "Only the owner can archive the space")
+	}
+
+	var space models.Space
+	if err := db.DB.First(&space, spaceID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
+	}
+	if (space.ArchivedAt != nil) == archived {
+		if archived {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Space is already archived"})
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Space is not archived"})
+	}
+
+	action := models.AuditSpaceUnarchived
+	var archivedAt *time.Time
+	if archived {
+		// Nobody can control the timer of an archived space, stop it first
+		if _, err := timer.Apply(spaceID, userID, timer.ActionReset); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not stop the space timer"})
+		}
+		now := time.Now()
+		archivedAt = &now
+		action = models.AuditSpaceArchived
+	}
+
+	if err := db.DB.Model(&space).Update("archived_at", archivedAt).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update space"})
+	}
+	space.ArchivedAt = archivedAt
+
+	models.RecordAudit(db.DB, spaceID, &userID, action, "space", &spaceID, nil, nil)
+
+	return c.JSON(space)
+}
+
+// Get Deleted Spaces (the user's own, still restorable)
+func GetDeletedSpaces(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	cfg := config.LoadConfig()
+
+	var spaces []models.Space
+	if err := db.DB.Unscoped().
+		Where("owner_id = ? AND deleted_at IS NOT NULL AND deleted_at > ?", userID, time.Now().Add(-cfg.SpaceRestoreWindow)).
+		Order("deleted_at desc").
+		Find(&spaces).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch spaces"})
+	}
+
+	type DeletedSpace struct {
+		models.Space
+		DeletedAt       time.Time `json:"deleted_at"`
+		RestorableUntil time.Time `json:"restorable_until"`
+	}
+	response := make([]DeletedSpace, 0, len(spaces))
+	for _, space := range spaces {
+		response = append(response, DeletedSpace{
+			Space:           space,
+			DeletedAt:       space.DeletedAt.Time,
+			RestorableUntil: space.DeletedAt.Time.Add(cfg.SpaceRestoreWindow),
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// Restore Space (owner only, within the restore window)
+func RestoreSpace(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	cfg := config.LoadConfig()
+
+	var space models.Space
+	if err := db.DB.Unscoped().
+		Where("id = ? AND owner_id = ? AND deleted_at IS NOT NULL AND deleted_at > ?", spaceID, userID, time.Now().Add(-cfg.SpaceRestoreWindow)).
+		First(&space).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No restorable space found"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// A restored space counts toward the owner's plan again
+		if err := entitlements.CheckOwnedSpaces(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&space).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		// Spaces deleted before deletion became restorable lost their members,
+		// so the owner at least gets their own membership back
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SpaceMember{
+			SpaceID:  spaceID,
+			UserID:   userID,
+			Role:     models.SpaceRoleOwner,
+			JoinedAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, spaceID, &userID, models.AuditSpaceRestored, "space", &spaceID, nil, nil)
+	})
+	var quotaErr *entitlements.QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not restore space"})
+	}
+
+	space.DeletedAt = gorm.DeletedAt{}
+	return c.JSON(space)
+}
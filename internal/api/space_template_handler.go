@@ -0,0 +1,428 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invites sent to the members of a cloned space stay open this long
+const cloneInviteTTL = 7 * 24 * time.Hour
+
+// snapshotSpace captures the configuration of a space for a template or a
+// clone.
+func snapshotSpace(space models.Space) (models.SpaceTemplateSettings, error) {
+	settings := models.SpaceTemplateSettings{
+		PomodoroWorkDuration:       space.PomodoroWorkDuration,
+		PomodoroShortBreakDuration: space.PomodoroShortBreakDuration,
+		PomodoroLongBreakDuration:  space.PomodoroLongBreakDuration,
+		PomodoroRounds:             space.PomodoroRounds,
+		Visibility:                 space.Visibility,
+		Description:                space.Description,
+		Tags:                       space.Tags,
+		Members:                    []models.SpaceTemplateMember{},
+		PinnedMessages:             []models.SpaceTemplatePin{},
+		Events:                     []models.SpaceTemplateEvent{},
+	}
+
+	var members []models.SpaceMember
+	if err := db.DB.Where("space_id = ?", space.ID).Order("joined_at").Find(&members).Error; err != nil {
+		return settings, err
+	}
+	for _, member := range members {
+		settings.Members = append(settings.Members, models.SpaceTemplateMember{UserID: member.UserID, Role: member.Role})
+	}
+
+	cutoff, err := entitlements.MessageCutoff(db.DB, space.ID)
+	if err != nil {
+		return settings, err
+	}
+	query := db.DB.Model(&models.Message{}).Where("space_id = ? AND pinned_at IS NOT NULL", space.ID)
+	if cutoff != nil {
+		query = query.Where("created_at >= ?", *cutoff)
+	}
+	var pinned []models.Message
+	if err := query.Order("pinned_at").Find(&pinned).Error; err != nil {
+		return settings, err
+	}
+	for _, message := range pinned {
+		settings.PinnedMessages = append(settings.PinnedMessages, models.SpaceTemplatePin{MessageID: message.ID})
+	}
+
+	var events []models.SpaceEvent
+	if err := db.DB.Where("space_id = ? AND recurrence <> '' AND (recurrence_until IS NULL OR recurrence_until > ?)", space.ID, time.Now()).
+		Order("starts_at").
+		Find(&events).Error; err != nil {
+		return settings, err
+	}
+	for _, event := range events {
+		settings.Events = append(settings.Events, models.SpaceTemplateEvent{
+			Title:           event.Title,
+			Description:     event.Description,
+			StartsAt:        event.StartsAt,
+			DurationMinutes: event.DurationMinutes,
+			Recurrence:      event.Recurrence,
+			RecurrenceUntil: event.RecurrenceUntil,
+			AutoStartTimer:  event.AutoStartTimer,
+		})
+	}
+	return settings, nil
+}
+
+// createSpaceFromSettings creates a space owned by ownerID with a template's
+// configuration. With inviteMembers every other member of the template gets
+// a personal invite with their old role; owners come back as admins.
+func createSpaceFromSettings(tx *gorm.DB, ownerID uuid.UUID, name string, settings models.SpaceTemplateSettings, inviteMembers bool) (models.Space, []InviteResponse, error) {
+	invites := []InviteResponse{}
+	if err := entitlements.CheckOwnedSpaces(tx, ownerID); err != nil {
+		return models.Space{}, invites, err
+	}
+
+	visibility := settings.Visibility
+	if !isValidVisibility(visibility) {
+		visibility = models.SpaceVisibilityPrivate
+	}
+	space := models.Space{
+		Name:                       name,
+		OwnerID:                    ownerID,
+		PomodoroWorkDuration:       settings.PomodoroWorkDuration,
+		PomodoroShortBreakDuration: settings.PomodoroShortBreakDuration,
+		PomodoroLongBreakDuration:  settings.PomodoroLongBreakDuration,
+		PomodoroRounds:             settings.PomodoroRounds,
+		Visibility:                 visibility,
+		Description:                settings.Description,
+		Tags:                       settings.Tags,
+	}
+	if err := tx.Create(&space).Error; err != nil {
+		return space, invites, err
+	}
+	if err := tx.Create(&models.SpaceMember{
+		SpaceID:  space.ID,
+		UserID:   ownerID,
+		Role:     models.SpaceRoleOwner,
+		JoinedAt: time.Now(),
+	}).Error; err != nil {
+		return space, invites, err
+	}
+
+	// Pins are looked up again so messages deleted since the snapshot stay
+	// gone. The owner posts the copies, crediting the original sender.
+	messageIDs := make([]uuid.UUID, 0, len(settings.PinnedMessages))
+	for _, pin := range settings.PinnedMessages {
+		messageIDs = append(messageIDs, pin.MessageID)
+	}
+	var messages []models.Message
+	if len(messageIDs) > 0 {
+		if err := tx.Where("id IN ?", messageIDs).Find(&messages).Error; err != nil {
+			return space, invites, err
+		}
+	}
+	byID := make(map[uuid.UUID]models.Message, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+	now := time.Now()
+	for _, pin := range settings.PinnedMessages {
+		message, ok := byID[pin.MessageID]
+		if !ok {
+			continue
+		}
+		if err := tx.Create(&models.Message{
+			SpaceID:          space.ID,
+			SenderID:         ownerID,
+			Content:          message.Content,
+			PinnedAt:         &now,
+			PinnedByID:       &ownerID,
+			OriginalSenderID: &message.SenderID,
+		}).Error; err != nil {
+			return space, invites, err
+		}
+	}
+
+	for _, template := range settings.Events {
+		event := models.SpaceEvent{
+			SpaceID:         space.ID,
+			CreatedByID:     ownerID,
+			Title:           template.Title,
+			Description:     template.Description,
+			StartsAt:        template.StartsAt,
+			DurationMinutes: template.DurationMinutes,
+			Recurrence:      template.Recurrence,
+			RecurrenceUntil: template.RecurrenceUntil,
+			AutoStartTimer:  template.AutoStartTimer,
+		}
+		if msg := validateEvent(&event); msg != "" {
+			continue
+		}
+		// Keep the weekday and time of day, starting from the next occurrence.
+		// Series that have ended since are left out.
+		if !event.StartsAt.After(now) {
+			next := event.Occurrences(now, now.Add(event.Period()))
+			if len(next) == 0 {
+				continue
+			}
+			event.StartsAt = next[0]
+		}
+		if err := tx.Select("*").Omit("CreatedBy").Create(&event).Error; err != nil {
+			return space, invites, err
+		}
+	}
+
+	if !inviteMembers {
+		return space, invites, nil
+	}
+
+	// Accounts deleted since the template was saved are skipped
+	userIDs := make([]uuid.UUID, 0, len(settings.Members))
+	for _, member := range settings.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	exists, err := existingUsers(tx, userIDs)
+	if err != nil {
+		return space, invites, err
+	}
+
+	expiresAt := now.Add(cloneInviteTTL)
+	for _, member := range settings.Members {
+		if member.UserID == ownerID || !exists[member.UserID] {
+			continue
+		}
+		role := member.Role
+		if role == models.SpaceRoleOwner {
+			role = models.SpaceRoleAdmin
+		}
+		if !permissions.IsValidRole(role) {
+			role = models.SpaceRoleMember
+		}
+
+		code, err := utils.GenerateOpaqueToken(8)
+		if err != nil {
+			return space, invites, err
+		}
+		inviteeID := member.UserID
+		invite := models.SpaceInvite{
+			SpaceID:     space.ID,
+			Code:        code,
+			CreatedByID: ownerID,
+			Role:        role,
+			MaxUses:     1,
+			ExpiresAt:   &expiresAt,
+			InviteeID:   &inviteeID,
+		}
+		if err := tx.Create(&invite).Error; err != nil {
+			return space, invites, err
+		}
+		if err := models.RecordAudit(tx, space.ID, &ownerID, models.AuditInviteCreated, "invite", &invite.ID, nil,
+			map[string]interface{}{"role": invite.Role, "invitee_id": inviteeID, "expires_at": invite.ExpiresAt}); err != nil {
+			return space, invites, err
+		}
+		invites = append(invites, InviteResponse{SpaceInvite: invite, URL: inviteURL(invite.Code)})
+	}
+	return space, invites, nil
+}
+
+// existingUsers reports which of the given users still have an account.
+func existingUsers(tx *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	exists := make(map[uuid.UUID]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return exists, nil
+	}
+	var existing []uuid.UUID
+	if err := tx.Model(&models.User{}).Where("id IN ?", userIDs).Pluck("id", &existing).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range existing {
+		exists[id] = true
+	}
+	return exists, nil
+}
+
+// createdFromSettings responds to a space created from a template or clone.
+func createdFromSettings(c *fiber.Ctx, ownerID uuid.UUID, name string, settings models.SpaceTemplateSettings, inviteMembers bool) error {
+	var space models.Space
+	var invites []InviteResponse
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		space, invites, err = createSpaceFromSettings(tx, ownerID, name, settings, inviteMembers)
+		return err
+	})
+	var quotaErr *entitlements.QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create space"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"space": space, "invites": invites})
+}
+
+// Save Space as Template (admins only)
+func SaveSpaceTemplate(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		Name string `json:"name"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.EditSettings); err != nil {
+		return authorizeError(c, err, "Only admins can save a space as a template")
+	}
+
+	var space models.Space
+	if err := db.DB.First(&space, spaceID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
+	}
+
+	settings, err := snapshotSpace(space)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save template"})
+	}
+
+	template := models.SpaceTemplate{
+		OwnerID:       userID,
+		SourceSpaceID: &space.ID,
+		Name:          strings.TrimSpace(req.Name),
+		Settings:      settings,
+	}
+	if template.Name == "" {
+		template.Name = space.Name
+	}
+	if err := db.DB.Create(&template).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save template"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(template)
+}
+
+// Get My Templates
+func GetTemplates(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var templates []models.SpaceTemplate
+	if err := db.DB.Where("owner_id = ?", userID).Order("created_at desc").Find(&templates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch templates"})
+	}
+
+	return c.JSON(templates)
+}
+
+// Delete Template
+func DeleteTemplate(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	templateID, err := uuid.Parse(c.Params("templateId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid template ID"})
+	}
+
+	result := db.DB.Where("id = ? AND owner_id = ?", templateID, userID).Delete(&models.SpaceTemplate{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete template"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Template not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Template deleted successfully"})
+}
+
+// Create Space from Template
+func CreateSpaceFromTemplate(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	type Request struct {
+		TemplateID    uuid.UUID `json:"template_id"`
+		Name          string    `json:"name"`
+		InviteMembers bool      `json:"invite_members"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var template models.SpaceTemplate
+	if err := db.DB.Where("id = ? AND owner_id = ?", req.TemplateID, userID).First(&template).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Template not found"})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = template.Name
+	}
+
+	return createdFromSettings(c, userID, name, template.Settings, req.InviteMembers)
+}
+
+// Clone Space (admins only); the current user owns the copy
+func CloneSpace(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		Name          string `json:"name"`
+		InviteMembers bool   `json:"invite_members"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.EditSettings); err != nil {
+		return authorizeError(c, err, "Only admins can clone a space")
+	}
+
+	var space models.Space
+	if err := db.DB.First(&space, spaceID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Space not found"})
+	}
+
+	settings, err := snapshotSpace(space)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not clone space"})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = space.Name + " (copy)"
+	}
+
+	return createdFromSettings(c, userID, name, settings, req.InviteMembers)
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const ownershipTransferTTL = 7 * 24 * time.Hour
+
+var errTransferNotFound = errors.New("transfer not found")
+
+// Transfer Ownership (offer the space to another member)
+func TransferOwnership(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.UserID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You already own this space"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace)
+	if err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+	if membership.Role != models.SpaceRoleOwner {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the owner can transfer the space"})
+	}
+
+	var target models.SpaceMember
+	if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, req.UserID).First(&target).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Ownership can only be transferred to a member of the space"})
+	}
+
+	transfer := models.SpaceOwnershipTransfer{
+		SpaceID:    spaceID,
+		FromUserID: userID,
+		ToUserID:   req.UserID,
+		Status:     "pending",
+		ExpiresAt:  time.Now().Add(ownershipTransferTTL),
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Only one offer can be open at a time
+		if err := tx.Model(&models.SpaceOwnershipTransfer{}).
+			Where("space_id = ? AND status = ?", spaceID, "pending").
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&transfer).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, spaceID, &userID, models.AuditTransferOffered, "transfer", &transfer.ID, nil,
+			map[string]interface{}{"to_user_id": req.UserID})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create transfer"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(transfer)
+}
+
+// Cancel Ownership Transfer
+func CancelOwnershipTransfer(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	result := db.DB.Model(&models.SpaceOwnershipTransfer{}).
+		Where("space_id = ? AND from_user_id = ? AND status = ?", spaceID, userID, "pending").
+		Update("status", "cancelled")
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not cancel transfer"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No pending transfer"})
+	}
+
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditTransferCancelled, "space", &spaceID, nil, nil)
+
+	return c.JSON(fiber.Map{"message": "Transfer cancelled"})
+}
+
+// Get Incoming Ownership Transfers
+func GetIncomingTransfers(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var transfers []models.SpaceOwnershipTransfer
+	if err := db.DB.Preload("Space").Preload("FromUser").
+		Where("to_user_id = ? AND status = ? AND expires_at > ?", userID, "pending", time.Now()).
+		Order("created_at desc").
+		Find(&transfers).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch transfers"})
+	}
+
+	return c.JSON(transfers)
+}
+
+// Accept Ownership Transfer
+func AcceptOwnershipTransfer(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	transferID, err := uuid.Parse(c.Params("transferId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid transfer ID"})
+	}
+
+	var transfer models.SpaceOwnershipTransfer
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND to_user_id = ?", transferID, userID).
+			First(&transfer).Error; err != nil || !transfer.IsPending() {
+			return errTransferNotFound
+		}
+
+		// Both sides must still be where they were when the offer was made
+		var space models.Space
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&space, transfer.SpaceID).Error; err != nil || space.OwnerID != transfer.FromUserID {
+			return errTransferNotFound
+		}
+		var member int64
+		tx.Model(&models.SpaceMember{}).Where("space_id = ? AND user_id = ?", space.ID, userID).Count(&member)
+		if member == 0 {
+			return errTransferNotFound
+		}
+
+		// Owned spaces are limited, taking one over counts too
+		if err := entitlements.CheckOwnedSpaces(tx, userID); err != nil {
+			return err
+		}
+
+		if err := transferSpace(tx, space.ID, transfer.FromUserID, userID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transfer.Status = "accepted"
+		transfer.RespondedAt = &now
+		if err := tx.Model(&transfer).Updates(map[string]interface{}{"status": "accepted", "responded_at": now}).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, space.ID, &userID, models.AuditTransferAccepted, "transfer", &transfer.ID,
+			map[string]interface{}{"owner_id": transfer.FromUserID}, map[string]interface{}{"owner_id": userID})
+	})
+	if errors.Is(err, errTransferNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Transfer not found or no longer valid"})
+	}
+	var quotaErr *entitlements.QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not accept transfer"})
+	}
+
+	return c.JSON(fiber.Map{"message": "You are now the owner of this space", "transfer": transfer})
+}
+
+// Decline Ownership Transfer
+func DeclineOwnershipTransfer(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	transferID, err := uuid.Parse(c.Params("transferId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid transfer ID"})
+	}
+
+	var transfer models.SpaceOwnershipTransfer
+	if err := db.DB.Where("id = ? AND to_user_id = ?", transferID, userID).First(&transfer).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Transfer not found"})
+	}
+
+	result := db.DB.Model(&models.SpaceOwnershipTransfer{}).
+		Where("id = ? AND status = ?", transfer.ID, "pending").
+		Updates(map[string]interface{}{"status": "declined", "responded_at": time.Now()})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not decline transfer"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Transfer not found"})
+	}
+
+	models.RecordAudit(db.DB, transfer.SpaceID, &userID, models.AuditTransferDeclined, "transfer", &transfer.ID, nil, nil)
+
+	return c.JSON(fiber.Map{"message": "Transfer declined"})
+}
+
+// transferSpace makes newOwner the owner of the space and demotes the previous
+// owner to admin.
+func transferSpace(tx *gorm.DB, spaceID, oldOwner, newOwner uuid.UUID) error {
+	if err := tx.Model(&models.Space{}).Where("id = ?", spaceID).Update("owner_id", newOwner).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.SpaceMember{}).Where("space_id = ? AND user_id = ?", spaceID, oldOwner).
+		Update("role", models.SpaceRoleAdmin).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.SpaceMember{}).Where("space_id = ? AND user_id = ?", spaceID, newOwner).
+		Update("role", models.SpaceRoleOwner).Error; err != nil {
+		return err
+	}
+	return nil
+}
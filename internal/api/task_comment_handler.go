@@ -0,0 +1,148 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/ws"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Get Task Comments
+func GetTaskComments(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var comments []models.SpaceTaskComment
+	if err := db.DB.Where("space_id = ? AND task_id = ?", spaceID, taskID).
+		Order("created_at asc").
+		Preload("User").
+		Find(&comments).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch comments"})
+	}
+
+	return c.JSON(comments)
+}
+
+// Add Task Comment
+func AddTaskComment(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+
+	type Request struct {
+		Content string `json:"content"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Content == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Content is required"})
+	}
+
+	// Commenting is open to everyone who may chat in the space
+	if _, err := permissions.Authorize(spaceID, userID, permissions.SendMessages); err != nil {
+		return authorizeError(c, err, "You cannot comment in this space")
+	}
+
+	var task models.SpaceTask
+	if err := db.DB.Select("id").Where("id = ? AND space_id = ?", taskID, spaceID).First(&task).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+
+	comment := models.SpaceTaskComment{
+		SpaceID: spaceID,
+		TaskID:  taskID,
+		UserID:  userID,
+		Content: req.Content,
+	}
+	if err := db.DB.Create(&comment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not add comment"})
+	}
+
+	db.DB.Preload("User").First(&comment, comment.ID)
+	ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeTaskCommentAdded, comment)
+
+	return c.Status(fiber.StatusCreated).JSON(comment)
+}
+
+// Delete Task Comment (own comments, or anyone's for moderators and above)
+func DeleteTaskComment(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+	commentID, err := uuid.Parse(c.Params("commentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment ID"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace)
+	if err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	var comment models.SpaceTaskComment
+	if err := db.DB.Where("id = ? AND task_id = ? AND space_id = ?", commentID, taskID, spaceID).First(&comment).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+
+	if comment.UserID != userID {
+		if !permissions.Can(membership.Role, permissions.DeleteTasks) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only delete your own comments"})
+		}
+		if !permissions.Outranks(membership.Role, memberRole(spaceID, comment.UserID)) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot delete comments of a member with an equal or higher role"})
+		}
+	}
+
+	if err := db.DB.Delete(&comment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete comment"})
+	}
+
+	models.RecordAudit(db.DB, spaceID, &userID, models.AuditTaskCommentDeleted, "task_comment", &comment.ID,
+		map[string]interface{}{"task_id": comment.TaskID, "user_id": comment.UserID}, nil)
+
+	ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeTaskCommentDeleted, fiber.Map{"task_id": comment.TaskID, "comment_id": comment.ID})
+
+	return c.JSON(fiber.Map{"message": "Comment deleted successfully"})
+}
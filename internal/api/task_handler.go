@@ -0,0 +1,431 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/ws"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errInvalidAssignee   = errors.New("assignee is not a member of the space")
+	errInvalidTaskStatus = errors.New("invalid task status")
+)
+
+func isValidTaskStatus(status string) bool {
+	switch status {
+	case models.TaskStatusBacklog, models.TaskStatusInProgress, models.TaskStatusDone:
+		return true
+	}
+	return false
+}
+
+// lockBoard serializes changes to the positions on a space's task board.
+func lockBoard(tx *gorm.DB, spaceID uuid.UUID) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Space{}, spaceID).Error
+}
+
+// parseAssignee turns a requested assignee into a user ID, nil for "", and
+// checks that they belong to the space.
+func parseAssignee(spaceID uuid.UUID, raw string) (*uuid.UUID, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	assigneeID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, errInvalidAssignee
+	}
+	var count int64
+	if err := db.DB.Model(&models.SpaceMember{}).Where("space_id = ? AND user_id = ?", spaceID, assigneeID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errInvalidAssignee
+	}
+	return &assigneeID, nil
+}
+
+func loadSpaceTask(spaceID, taskID uuid.UUID) (models.SpaceTask, error) {
+	var task models.SpaceTask
+	err := db.DB.Where("id = ? AND space_id = ?", taskID, spaceID).
+		Preload("CreatedBy").
+		Preload("Assignee").
+		First(&task).Error
+	return task, err
+}
+
+// Get Space Tasks
+func GetSpaceTasks(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	query := db.DB.Where("space_id = ?", spaceID)
+	if status := c.Query("status"); status != "" {
+		if !isValidTaskStatus(status) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status must be 'backlog', 'in_progress' or 'done'"})
+		}
+		query = query.Where("status = ?", status)
+	}
+	if raw := c.Query("assignee_id"); raw != "" {
+		assigneeID, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid assignee ID"})
+		}
+		query = query.Where("assignee_id = ?", assigneeID)
+	}
+
+	var tasks []models.SpaceTask
+	if err := query.
+		Order("status").
+		Order("position").
+		Preload("CreatedBy").
+		Preload("Assignee").
+		Find(&tasks).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch tasks"})
+	}
+
+	return c.JSON(tasks)
+}
+
+// Create Space Task (added to the end of its column)
+func CreateSpaceTask(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	type Request struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Status      string `json:"status"`
+		AssigneeID  string `json:"assignee_id"`
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
+	}
+	if len(req.Title) > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title can be at most 200 characters long"})
+	}
+	if req.Status == "" {
+		req.Status = models.TaskStatusBacklog
+	}
+	if !isValidTaskStatus(req.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status must be 'backlog', 'in_progress' or 'done'"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageTasks); err != nil {
+		return authorizeError(c, err, "Guests cannot add tasks")
+	}
+
+	assigneeID, err := parseAssignee(spaceID, req.AssigneeID)
+	if errors.Is(err, errInvalidAssignee) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tasks can only be assigned to members of the space"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create task"})
+	}
+
+	task := models.SpaceTask{
+		SpaceID:     spaceID,
+		CreatedByID: userID,
+		AssigneeID:  assigneeID,
+		Title:       req.Title,
+		Description: strings.TrimSpace(req.Description),
+		Status:      req.Status,
+	}
+	if task.Status == models.TaskStatusDone {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockBoard(tx, spaceID); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.SpaceTask{}).Where("space_id = ? AND status = ?", spaceID, task.Status).Count(&count).Error; err != nil {
+			return err
+		}
+		task.Position = int(count)
+		return tx.Create(&task).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create task"})
+	}
+
+	task, _ = loadSpaceTask(spaceID, task.ID)
+	ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeTaskCreated, task)
+
+	return c.Status(fiber.StatusCreated).JSON(task)
+}
+
+// Update Space Task (title, description or assignee)
+func UpdateSpaceTask(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+
+	type Request struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		AssigneeID  *string `json:"assignee_id"` // "" unassigns
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageTasks); err != nil {
+		return authorizeError(c, err, "Guests cannot edit tasks")
+	}
+
+	task, err := loadSpaceTask(spaceID, taskID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
+		}
+		if len(title) > 200 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title can be at most 200 characters long"})
+		}
+		updates["title"] = title
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.AssigneeID != nil {
+		assigneeID, err := parseAssignee(spaceID, *req.AssigneeID)
+		if errors.Is(err, errInvalidAssignee) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tasks can only be assigned to members of the space"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update task"})
+		}
+		updates["assignee_id"] = assigneeID
+	}
+	if len(updates) == 0 {
+		return c.JSON(task)
+	}
+
+	if err := db.DB.Model(&task).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update task"})
+	}
+
+	task, _ = loadSpaceTask(spaceID, task.ID)
+	ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeTaskUpdated, task)
+
+	return c.JSON(task)
+}
+
+// Move Space Task to another column or position
+func MoveSpaceTask(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+
+	type Request struct {
+		Status   string `json:"status"`
+		Position *int   `json:"position"` // Defaults to the end of the column
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ManageTasks); err != nil {
+		return authorizeError(c, err, "Guests cannot move tasks")
+	}
+
+	var task models.SpaceTask
+	var fromStatus string
+	var fromPosition int
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockBoard(tx, spaceID); err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND space_id = ?", taskID, spaceID).First(&task).Error; err != nil {
+			return err
+		}
+		fromStatus, fromPosition = task.Status, task.Position
+
+		toStatus := req.Status
+		if toStatus == "" {
+			toStatus = task.Status
+		}
+		if !isValidTaskStatus(toStatus) {
+			return errInvalidTaskStatus
+		}
+
+		// Where the task may go in its new column, without counting itself
+		var count int64
+		if err := tx.Model(&models.SpaceTask{}).
+			Where("space_id = ? AND status = ? AND id <> ?", spaceID, toStatus, task.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		toPosition := int(count)
+		if req.Position != nil && *req.Position >= 0 && *req.Position < toPosition {
+			toPosition = *req.Position
+		}
+
+		// Close the gap it leaves and open one where it lands
+		if err := tx.Model(&models.SpaceTask{}).
+			Where("space_id = ? AND status = ? AND position > ?", spaceID, fromStatus, fromPosition).
+			Update("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SpaceTask{}).
+			Where("space_id = ? AND status = ? AND position >= ? AND id <> ?", spaceID, toStatus, toPosition, task.ID).
+			Update("position", gorm.Expr("position + 1")).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"status": toStatus, "position": toPosition}
+		if toStatus != fromStatus {
+			updates["completed_at"] = nil
+			if toStatus == models.TaskStatusDone {
+				updates["completed_at"] = time.Now()
+			}
+		}
+		return tx.Model(&task).Updates(updates).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+	if errors.Is(err, errInvalidTaskStatus) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status must be 'backlog', 'in_progress' or 'done'"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not move task"})
+	}
+
+	task, _ = loadSpaceTask(spaceID, task.ID)
+	// Clients shift the other cards of both columns from this
+	ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeTaskMoved, fiber.Map{
+		"task":          task,
+		"from_status":   fromStatus,
+		"from_position": fromPosition,
+	})
+
+	return c.JSON(task)
+}
+
+// Delete Space Task (own tasks, or anyone's for moderators and above)
+func DeleteSpaceTask(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+
+	membership, err := permissions.Authorize(spaceID, userID, permissions.ManageTasks)
+	if err != nil {
+		return authorizeError(c, err, "Guests cannot delete tasks")
+	}
+
+	var task models.SpaceTask
+	if err := db.DB.Where("id = ? AND space_id = ?", taskID, spaceID).First(&task).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+
+	if task.CreatedByID != userID && !permissions.Can(membership.Role, permissions.DeleteTasks) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only delete tasks you created"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockBoard(tx, spaceID); err != nil {
+			return err
+		}
+		// Re-read the position, it may have moved since
+		if err := tx.First(&task, task.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&task).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SpaceTask{}).
+			Where("space_id = ? AND status = ? AND position > ?", spaceID, task.Status, task.Position).
+			Update("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, spaceID, &userID, models.AuditTaskDeleted, "task", &task.ID,
+			map[string]interface{}{"title": task.Title, "created_by_id": task.CreatedByID, "status": task.Status}, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete task"})
+	}
+
+	ws.GlobalHub.BroadcastToSpace(spaceID, ws.TypeTaskDeleted, fiber.Map{
+		"task_id":  task.ID,
+		"status":   task.Status,
+		"position": task.Position,
+	})
+
+	return c.JSON(fiber.Map{"message": "Task deleted successfully"})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/timer"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Get Space Timer
+func GetSpaceTimer(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
+		return authorizeError(c, err, "Access denied")
+	}
+
+	state, err := timer.Get(spaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch timer"})
+	}
+
+	return c.JSON(state)
+}
+
+// Control Space Timer (start, pause, resume, skip or reset)
+func ControlSpaceTimer(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	spaceID, err := uuid.Parse(c.Params("spaceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid space ID"})
+	}
+
+	if _, err := permissions.Authorize(spaceID, userID, permissions.ControlTimer); err != nil {
+		return authorizeError(c, err, "You cannot control the timer of this space")
+	}
+
+	state, err := timer.Apply(spaceID, userID, c.Params("action"))
+	if errors.Is(err, timer.ErrInvalidAction) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Action must be 'start', 'pause', 'resume', 'skip' or 'reset'"})
+	}
+	if errors.Is(err, timer.ErrInvalidTransition) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The timer cannot " + c.Params("action") + " right now"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update timer"})
+	}
+
+	return c.JSON(state)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Personal access tokens are recognisable by this prefix, which is how the
+// auth middleware tells them apart from JWTs.
+const patPrefix = "pph_"
+
+// patScopes lists every scope a personal access token can be granted.
+var patScopes = map[string]bool{
+	"pomodoro:read": true, "pomodoro:write": true,
+	"todos:read": true, "todos:write": true,
+	"habits:read": true, "habits:write": true,
+	"posts:read": true, "posts:write": true,
+	"friends:read": true, "friends:write": true,
+	"profile:read": true, "profile:write": true,
+	"spaces:read": true, "spaces:write": true,
+}
+
+// patScopeFor returns the scope a personal access token needs for the
+// request, or "" when tokens may not be used for it at all (account and
+// security settings stay reserved for interactive logins).
+func patScopeFor(c *fiber.Ctx) string {
+	path := strings.TrimPrefix(c.Path(), "/api/v1/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	area := segments[0]
+	switch area {
+	case "pomodoro", "todos", "habits", "posts", "friends", "spaces":
+	case "invites", "templates":
+		area = "spaces"
+	case "users":
+		// Only the profile itself; sessions, tokens, password and 2FA are off limits
+		if len(segments) > 1 && segments[1] == "me" && len(segments) > 2 {
+			return ""
+		}
+		area = "profile"
+	default:
+		return ""
+	}
+
+	if c.Method() == fiber.MethodGet {
+		return area + ":read"
+	}
+	return area + ":write"
+}
+
+// authenticateAccessToken authenticates a request made with a personal
+// access token instead of a JWT.
+func authenticateAccessToken(c *fiber.Ctx, token string) error {
+	var pat models.PersonalAccessToken
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(token)).First(&pat).Error; err != nil || !pat.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired access token"})
+	}
+
+	scope := patScopeFor(c)
+	if scope == "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access tokens cannot be used for this endpoint"})
+	}
+	if !pat.HasScope(scope) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access token is missing the " + scope + " scope"})
+	}
+
+	// Track usage without writing on every request
+	if pat.LastUsedAt == nil || time.Since(*pat.LastUsedAt) > time.Minute {
+		db.DB.Model(&pat).UpdateColumns(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": c.IP(),
+		})
+	}
+
+	c.Locals("user_id", pat.UserID)
+	c.Locals("access_token_id", pat.ID)
+	return c.Next()
+}
+
+// Get Access Tokens
+func GetAccessTokens(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var tokens []models.PersonalAccessToken
+	if err := db.DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at desc").
+		Find(&tokens).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch tokens"})
+	}
+
+	return c.JSON(tokens)
+}
+
+// Create Access Token (the plain token is only returned once)
+func CreateAccessToken(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	type Request struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"` // 0 = never expires
+	}
+	var req Request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token name is required"})
+	}
+	if len(req.Scopes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "At least one scope is required"})
+	}
+	seen := make(map[string]bool)
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !patScopes[scope] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown scope: " + scope})
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Expiry must not be negative"})
+	}
+
+	secret, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+	}
+	token := patPrefix + secret
+
+	pat := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:len(patPrefix)+6],
+		Scopes:    scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		pat.ExpiresAt = &expiresAt
+	}
+
+	if err := db.DB.Create(&pat).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create token"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"token":        token,
+		"access_token": pat,
+	})
+}
+
+// Delete Access Token (revokes it)
+func DeleteAccessToken(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	tokenID, err := uuid.Parse(c.Params("tokenId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid token ID"})
+	}
+
+	result := db.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not revoke token"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Token not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Token revoked successfully"})
+}
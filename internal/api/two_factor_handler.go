@@ -0,0 +1,322 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/bruteforce"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer        = "PomoHub"
+	mfaPendingTTL     = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type TwoFactorLoginRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	DeviceName   string `json:"device_name"`
+}
+
+// completeLogin finishes a successful first-factor login: users with 2FA get
+// an MFA challenge, everyone else gets a session straight away.
+func completeLogin(c *fiber.Ctx, user models.User, deviceName string) error {
+	if user.TOTPEnabled {
+		mfaToken, err := utils.GenerateMFAPendingToken(user.ID, mfaPendingTTL)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+		}
+		return c.JSON(MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int64(mfaPendingTTL.Seconds()),
+		})
+	}
+
+	resp, err := issueTokens(c, user, deviceName)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+	}
+	return c.JSON(resp)
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code. Both are consumed so they can't be replayed.
+func verifySecondFactor(user models.User, code, recoveryCode string) bool {
+	if !user.TOTPEnabled || user.TOTPSecret == "" {
+		return false
+	}
+
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return false
+		}
+		result := db.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return result.Error == nil && result.RowsAffected > 0
+	}
+
+	if recoveryCode != "" {
+		hash := utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))
+		result := db.DB.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hash).
+			Update("used_at", time.Now())
+		return result.Error == nil && result.RowsAffected > 0
+	}
+
+	return false
+}
+
+// replaceRecoveryCodes invalidates all existing recovery codes of a user and
+// returns a fresh set in plain text. Only hashes are stored.
+func replaceRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code)),
+		})
+	}
+
+	tx := db.DB.Begin()
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify Two-Factor Login (exchanges an MFA token plus code for a session)
+func VerifyTwoFactorLogin(c *fiber.Ctx) error {
+	var req TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	userID, err := utils.ParseMFAPendingToken(req.MFAToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	// Six digit codes are easy to guess without a limit
+	mfaKey := "mfa:" + user.ID.String()
+	if retryAfter := bruteforce.RetryAfter(c.Context(), mfaKey); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+
+	if !verifySecondFactor(user, req.Code, req.RecoveryCode) {
+		if lockout := bruteforce.Fail(c.Context(), bruteforce.AccountPolicy, mfaKey); lockout > 0 {
+			return tooManyAttempts(c, lockout)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	bruteforce.Succeed(c.Context(), mfaKey)
+
+	resp, err := issueTokens(c, user, req.DeviceName)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+	}
+
+	return c.JSON(resp)
+}
+
+// Setup Two-Factor (starts TOTP enrollment, not active until confirmed)
+func SetupTwoFactor(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate secret"})
+	}
+
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start two-factor setup"})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// Confirm Two-Factor (enables TOTP once the user proves their app works)
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if user.TOTPSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Start two-factor setup first"})
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	codes, err := replaceRecoveryCodes(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate recovery codes"})
+	}
+
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":   true,
+		"totp_last_step": step,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not enable two-factor authentication"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable Two-Factor (requires the password, if the account has one, and a second factor)
+func DisableTwoFactor(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+	// Accounts created through an identity provider may not have a password,
+	// the second factor alone has to do
+	if user.PasswordHash != "" && !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password is incorrect"})
+	}
+	if !verifySecondFactor(user, req.Code, req.RecoveryCode) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	tx := db.DB.Begin()
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":   false,
+		"totp_secret":    "",
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not disable two-factor authentication"})
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not disable two-factor authentication"})
+	}
+	tx.Commit()
+
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// Regenerate Recovery Codes (invalidates the previous set)
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+	if !verifySecondFactor(user, req.Code, "") {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	codes, err := replaceRecoveryCodes(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate recovery codes"})
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
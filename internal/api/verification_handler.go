@@ -0,0 +1,108 @@
+package api
+
+import (
+	"pomodoro-habit-backend/internal/bruteforce"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/mailer"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+// sendVerificationEmail mails the user a signed link confirming their address.
+func sendVerificationEmail(user models.User) error {
+	cfg := config.LoadConfig()
+	token, err := utils.GenerateEmailVerificationToken(user.ID, user.Email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	// The frontend page posts the token to Verify Email
+	link := strings.TrimRight(cfg.FrontendURL, "/") + "/verify-email?token=" + token
+	mailer.SendAsync(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your PomoHub email address",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Please confirm your email address by opening the link below:\n\n" +
+			link + "\n\n" +
+			"The link expires in 48 hours. If you didn't create a PomoHub account you can ignore this email.\n",
+	})
+	return nil
+}
+
+// Verify Email (token from the emailed link, as query param or JSON body)
+func VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := c.BodyParser(&req); err == nil {
+			token = req.Token
+		}
+	}
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Verification token is required"})
+	}
+
+	userID, email, err := utils.ParseEmailVerificationToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification token"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification token"})
+	}
+
+	// The token is only good for the address it was sent to
+	if !strings.EqualFold(user.Email, email) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification token"})
+	}
+
+	if !user.EmailVerified {
+		now := time.Now()
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+		if err := db.DB.Save(&user).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not verify email"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": "Email verified successfully"})
+}
+
+// Resend Verification Email
+func ResendVerification(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+
+	// Every request counts, so the endpoint can't be used to flood an inbox
+	accountKey := bruteforce.MailKey(bruteforce.AccountKey(req.Email))
+	ipKey := bruteforce.MailKey(bruteforce.IPKey(c.IP()))
+	if retryAfter := bruteforce.RetryAfter(c.Context(), accountKey, ipKey); retryAfter > 0 {
+		return tooManyAttempts(c, retryAfter)
+	}
+	bruteforce.Fail(c.Context(), bruteforce.MailPolicy, accountKey)
+	bruteforce.Fail(c.Context(), bruteforce.MailPolicy, ipKey)
+
+	// Same response whether or not the account exists, so this can't be used to probe emails
+	var user models.User
+	if result := db.DB.Where("email = ?", req.Email).First(&user); result.RowsAffected > 0 && !user.EmailVerified {
+		if err := sendVerificationEmail(user); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send verification email"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": "If the account exists and is unverified, a verification email has been sent"})
+}
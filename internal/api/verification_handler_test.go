@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/mailer"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/utils"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const testFrontendURL = "https://app.pomohub.test"
+
+var verificationLink = regexp.MustCompile(regexp.QuoteMeta(testFrontendURL) + `/verify-email\?token=(\S+)`)
+
+// setupMailOutbox points the mailer at a fresh outbox directory and the
+// links it writes at the test frontend.
+func setupMailOutbox(t *testing.T) string {
+	t.Helper()
+	t.Setenv("FRONTEND_URL", testFrontendURL)
+	if err := utils.LoadSigningKeys("", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	previous := mailer.Default
+	mailer.Default = &mailer.LogMailer{Dir: dir}
+	t.Cleanup(func() { mailer.Default = previous })
+	return dir
+}
+
+// readVerificationToken waits for the single mail sent in the background and
+// returns the token of the verification link in it.
+func readVerificationToken(t *testing.T, dir string) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		if len(files) == 1 {
+			data, err := os.ReadFile(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			match := verificationLink.FindStringSubmatch(string(data))
+			if match == nil {
+				t.Fatalf("no verification link to the frontend in mail:\n%s", data)
+			}
+			return match[1]
+		}
+		if len(files) > 1 || time.Now().After(deadline) {
+			t.Fatalf("found %d mails, want 1", len(files))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestVerificationEmailLinksToFrontend(t *testing.T) {
+	dir := setupMailOutbox(t)
+
+	user := models.User{ID: uuid.New(), Username: "ada", Email: "ada@example.com"}
+	if err := sendVerificationEmail(user); err != nil {
+		t.Fatal(err)
+	}
+
+	userID, email, err := utils.ParseEmailVerificationToken(readVerificationToken(t, dir))
+	if err != nil {
+		t.Fatalf("token from the mail does not parse: %v", err)
+	}
+	if userID != user.ID || email != user.Email {
+		t.Errorf("token is for %s <%s>, want %s <%s>", userID, email, user.ID, user.Email)
+	}
+}
+
+// TestRegisterThenVerify runs against a real database, e.g.
+// TEST_DATABASE_URL="host=localhost user=pomohub password=pomohub_secret dbname=pomohub_test port=5432 sslmode=disable"
+func TestRegisterThenVerify(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db.ConnectDB(&config.Config{DBUrl: dsn})
+	dir := setupMailOutbox(t)
+
+	app := fiber.New()
+	app.Post("/api/v1/auth/register", Register)
+	app.Post("/api/v1/auth/verify", VerifyEmail)
+
+	suffix := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	email := "verify_" + suffix + "@example.com"
+	body := `{"username": "verify_` + suffix + `", "email": "` + email + `", "password": "Correct-Horse1"}`
+	req := httptest.NewRequest("POST", "/api/v1/auth/register", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("register status = %d, want %d", resp.StatusCode, fiber.StatusCreated)
+	}
+
+	var user models.User
+	if err := db.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.DB.Where("user_id = ?", user.ID).Delete(&models.Session{})
+		db.DB.Unscoped().Delete(&user)
+	})
+	if user.EmailVerified {
+		t.Fatal("new account is verified before opening the link")
+	}
+
+	token := readVerificationToken(t, dir)
+	req = httptest.NewRequest("POST", "/api/v1/auth/verify", strings.NewReader(`{"token": "`+token+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("verify status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+
+	if err := db.DB.First(&user, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !user.EmailVerified || user.EmailVerifiedAt == nil {
+		t.Error("account is not verified after opening the link")
+	}
+}
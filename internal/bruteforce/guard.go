@@ -0,0 +1,103 @@
+package bruteforce
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Policy describes when repeated failures lock a key and for how long.
+// Every failure past Threshold doubles the lockout, up to MaxLockout.
+type Policy struct {
+	Threshold   int64
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	Window      time.Duration
+}
+
+var (
+	// AccountPolicy protects a single account from password guessing.
+	AccountPolicy = Policy{Threshold: 5, BaseLockout: 30 * time.Second, MaxLockout: 15 * time.Minute, Window: time.Hour}
+	// IPPolicy limits one address spraying guesses across many accounts.
+	IPPolicy = Policy{Threshold: 20, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}
+	// MailPolicy limits mails sent on request, where every request counts.
+	MailPolicy = Policy{Threshold: 3, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}
+)
+
+var store Store = newMemoryStore()
+
+// Setup stores counters in Valkey when a client is available.
+func Setup(client *redis.Client) {
+	if client == nil {
+		log.Println("Bruteforce: using in-memory failure counters")
+		store = newMemoryStore()
+		return
+	}
+	store = &fallbackStore{primary: &redisStore{client: client}, secondary: newMemoryStore()}
+}
+
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// MailKey separates mail requests from login failures of the same key.
+func MailKey(key string) string {
+	return "mail:" + key
+}
+
+// RetryAfter returns the longest remaining lockout among the keys, or 0 if
+// none of them is locked.
+func RetryAfter(ctx context.Context, keys ...string) time.Duration {
+	var longest time.Duration
+	for _, key := range keys {
+		d, err := store.LockedFor(ctx, key)
+		if err != nil {
+			log.Printf("Bruteforce: %v", err)
+			continue
+		}
+		if d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// Fail records a failed attempt for key and returns the lockout it caused, if any.
+func Fail(ctx context.Context, policy Policy, key string) time.Duration {
+	count, err := store.RecordFailure(ctx, key, policy.Window)
+	if err != nil {
+		log.Printf("Bruteforce: %v", err)
+		return 0
+	}
+	if count < policy.Threshold {
+		return 0
+	}
+
+	lockout := policy.BaseLockout
+	for i := policy.Threshold; i < count && lockout < policy.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > policy.MaxLockout {
+		lockout = policy.MaxLockout
+	}
+
+	if err := store.Lock(ctx, key, lockout); err != nil {
+		log.Printf("Bruteforce: %v", err)
+		return 0
+	}
+	return lockout
+}
+
+// Succeed clears the failure history of key.
+func Succeed(ctx context.Context, key string) {
+	if err := store.Reset(ctx, key); err != nil {
+		log.Printf("Bruteforce: %v", err)
+	}
+}
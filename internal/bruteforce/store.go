@@ -0,0 +1,174 @@
+package bruteforce
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store keeps failure counters and lockouts.
+type Store interface {
+	// RecordFailure increments the failure counter for key and returns the
+	// new count. Counters reset once window has passed since the first failure.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// LockedFor returns how long key stays locked, or 0 if it isn't.
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// redisStore shares counters between all API instances.
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, "bruteforce:fail:"+key)
+	pipe.ExpireNX(ctx, "bruteforce:fail:"+key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (s *redisStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	return s.client.Set(ctx, "bruteforce:lock:"+key, 1, d).Err()
+}
+
+func (s *redisStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, "bruteforce:lock:"+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *redisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, "bruteforce:fail:"+key, "bruteforce:lock:"+key).Err()
+}
+
+// memoryStore is used when Valkey is unavailable. Counters are per process.
+type memoryStore struct {
+	mutex   sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	count       int64
+	windowEnds  time.Time
+	lockedUntil time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *memoryStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sweep()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	if now.After(entry.windowEnds) {
+		entry.count = 0
+		entry.windowEnds = now.Add(window)
+	}
+	entry.count++
+	return entry.count, nil
+}
+
+func (s *memoryStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.lockedUntil = time.Now().Add(d)
+	return nil
+}
+
+func (s *memoryStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		if remaining := time.Until(entry.lockedUntil); remaining > 0 {
+			return remaining, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *memoryStore) Reset(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops entries that no longer carry any state. Callers hold the lock.
+func (s *memoryStore) sweep() {
+	now := time.Now()
+	for key, entry := range s.entries {
+		if now.After(entry.windowEnds) && now.After(entry.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// fallbackStore uses Valkey and switches to the in-memory store for any
+// call that fails, so a Valkey outage doesn't disable protection.
+type fallbackStore struct {
+	primary   Store
+	secondary Store
+}
+
+func (s *fallbackStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	count, err := s.primary.RecordFailure(ctx, key, window)
+	if err != nil {
+		log.Printf("Bruteforce: Valkey unavailable, using in-memory counters: %v", err)
+		return s.secondary.RecordFailure(ctx, key, window)
+	}
+	return count, nil
+}
+
+func (s *fallbackStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	if err := s.primary.Lock(ctx, key, d); err != nil {
+		log.Printf("Bruteforce: Valkey unavailable, using in-memory counters: %v", err)
+		return s.secondary.Lock(ctx, key, d)
+	}
+	return nil
+}
+
+func (s *fallbackStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	primary, err := s.primary.LockedFor(ctx, key)
+	if err != nil {
+		log.Printf("Bruteforce: Valkey unavailable, using in-memory counters: %v", err)
+	}
+	// Locks set while Valkey was down only exist in memory
+	secondary, _ := s.secondary.LockedFor(ctx, key)
+	if secondary > primary {
+		return secondary, nil
+	}
+	return primary, nil
+}
+
+func (s *fallbackStore) Reset(ctx context.Context, key string) error {
+	s.secondary.Reset(ctx, key)
+	return s.primary.Reset(ctx, key)
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"pomodoro-habit-backend/internal/config"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Client is the shared Valkey connection. It is nil when Valkey is not
+// reachable, in which case features fall back to in-process state.
+var Client *redis.Client
+
+func ConnectRedis(cfg *config.Config) {
+	opts, err := parseRedisURL(cfg.RedisUrl)
+	if err != nil {
+		log.Printf("Invalid REDIS_URL, continuing without Valkey: %v", err)
+		return
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Printf("Could not connect to Valkey at %s, continuing without it: %v", opts.Addr, err)
+		client.Close()
+		return
+	}
+
+	Client = client
+	log.Println("Connected to Valkey successfully")
+}
+
+// parseRedisURL accepts both a redis:// URL and a plain host:port address.
+func parseRedisURL(url string) (*redis.Options, error) {
+	if strings.Contains(url, "://") {
+		return redis.ParseURL(url)
+	}
+	return &redis.Options{Addr: url}, nil
+}
@@ -0,0 +1,181 @@
+package challenges
+
+import (
+	"log"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Update is a challenge together with its current progress, or its final
+// results once FinalizedAt is set.
+type Update struct {
+	Challenge models.SpaceChallenge   `json:"challenge"`
+	Progress  models.ChallengeResults `json:"progress"`
+}
+
+// Broadcaster delivers challenge updates to the members of a space.
+type Broadcaster func(spaceID uuid.UUID, update Update)
+
+var broadcast Broadcaster = func(uuid.UUID, Update) {}
+
+// Setup sets how challenge updates are broadcast.
+func Setup(b Broadcaster) {
+	broadcast = b
+}
+
+// IsValidMetric reports whether metric is one a challenge can count.
+func IsValidMetric(metric string) bool {
+	switch metric {
+	case models.ChallengeMetricFocusMinutes, models.ChallengeMetricPomodoros, models.ChallengeMetricHabitCheckIns:
+		return true
+	}
+	return false
+}
+
+// Progress computes how far the members of a space have come with a
+// challenge. Contributions are what each current member recorded between the
+// challenge's start and end, or until now while it is running.
+func Progress(tx *gorm.DB, challenge models.SpaceChallenge) (models.ChallengeResults, error) {
+	results := models.ChallengeResults{Target: challenge.Target, Contributions: []models.ChallengeContribution{}}
+
+	var members []struct {
+		UserID   uuid.UUID
+		Username string
+	}
+	if err := tx.Table("space_members").
+		Select("space_members.user_id, users.username").
+		Joins("JOIN users ON users.id = space_members.user_id").
+		Where("space_members.space_id = ?", challenge.SpaceID).
+		Scan(&members).Error; err != nil {
+		return results, err
+	}
+	if len(members) == 0 {
+		return results, nil
+	}
+	memberIDs := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		memberIDs = append(memberIDs, member.UserID)
+	}
+
+	var rows []struct {
+		UserID uuid.UUID
+		Value  int64
+	}
+	var query *gorm.DB
+	switch challenge.Metric {
+	case models.ChallengeMetricFocusMinutes, models.ChallengeMetricPomodoros:
+		value := "COALESCE(SUM(duration), 0)"
+		if challenge.Metric == models.ChallengeMetricPomodoros {
+			value = "COUNT(*)"
+		}
+		query = tx.Model(&models.PomodoroSession{}).
+			Select("user_id, "+value+" AS value").
+			Where("user_id IN ? AND completed AND created_at >= ? AND created_at < ?", memberIDs, challenge.StartsAt, challenge.EndsAt).
+			Group("user_id")
+	case models.ChallengeMetricHabitCheckIns:
+		query = tx.Table("habit_logs").
+			Select("habits.user_id, COUNT(*) AS value").
+			Joins("JOIN habits ON habits.id = habit_logs.habit_id AND habits.deleted_at IS NULL").
+			Where("habits.user_id IN ? AND habit_logs.completed AND habit_logs.created_at >= ? AND habit_logs.created_at < ?", memberIDs, challenge.StartsAt, challenge.EndsAt).
+			Group("habits.user_id")
+	default:
+		return results, nil
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return results, err
+	}
+
+	values := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		values[row.UserID] = row.Value
+		results.Total += row.Value
+	}
+	for _, member := range members {
+		contribution := models.ChallengeContribution{
+			UserID:   member.UserID,
+			Username: member.Username,
+			Value:    values[member.UserID],
+		}
+		if results.Total > 0 {
+			contribution.Share = float64(contribution.Value) / float64(results.Total)
+		}
+		results.Contributions = append(results.Contributions, contribution)
+	}
+	sort.SliceStable(results.Contributions, func(i, j int) bool {
+		a, b := results.Contributions[i], results.Contributions[j]
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		return a.Username < b.Username
+	})
+	results.Reached = results.Total >= results.Target
+	return results, nil
+}
+
+// Notify recomputes and broadcasts the running challenges the users may
+// just have contributed to, e.g. after they completed a pomodoro.
+func Notify(userIDs ...uuid.UUID) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var running []models.SpaceChallenge
+	if err := db.DB.
+		Joins("JOIN spaces ON spaces.id = space_challenges.space_id AND spaces.deleted_at IS NULL").
+		Where("space_challenges.space_id IN (?)", db.DB.Model(&models.SpaceMember{}).Select("space_id").Where("user_id IN ?", userIDs)).
+		Where("space_challenges.finalized_at IS NULL AND space_challenges.starts_at <= ? AND space_challenges.ends_at > ?", now, now).
+		Find(&running).Error; err != nil {
+		log.Printf("Could not load challenges to update: %v", err)
+		return
+	}
+
+	for _, challenge := range running {
+		progress, err := Progress(db.DB, challenge)
+		if err != nil {
+			log.Printf("Could not compute progress of challenge %s: %v", challenge.ID, err)
+			continue
+		}
+		if progress.Reached && challenge.ReachedAt == nil {
+			challenge.ReachedAt = &now
+			if err := db.DB.Model(&challenge).UpdateColumn("reached_at", now).Error; err != nil {
+				log.Printf("Could not mark challenge %s as reached: %v", challenge.ID, err)
+			}
+		}
+		broadcast(challenge.SpaceID, Update{Challenge: challenge, Progress: progress})
+	}
+}
+
+// Finalize computes the final results of a challenge that has ended, stores
+// them on the challenge and broadcasts them.
+func Finalize(challenge models.SpaceChallenge) error {
+	results, err := Progress(db.DB, challenge)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updates := models.SpaceChallenge{FinalizedAt: &now, Results: &results}
+	if results.Reached && challenge.ReachedAt == nil {
+		updates.ReachedAt = &now
+		challenge.ReachedAt = &now
+	}
+	// Only one finalizer wins should several run at once
+	result := db.DB.Model(&challenge).Where("finalized_at IS NULL").Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	challenge.FinalizedAt = &now
+	challenge.Results = &results
+	broadcast(challenge.SpaceID, Update{Challenge: challenge, Progress: results})
+	return nil
+}
@@ -3,15 +3,55 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port           string
-	DBUrl          string
-	RedisUrl       string
-	JWTSecret      string
+	Port            string
+	DBUrl           string
+	RedisUrl        string
+	JWTKeysDir      string // PEM signing keys, one file per key ID
+	JWTActiveKeyID  string // Key that signs new tokens, defaults to the last one by name
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	AppURL          string // Public base URL of this API
+	FrontendURL     string // Base URL of the web app that links in mails point to
+
+	// Deleted accounts are purged after this grace period
+	AccountDeletionGrace time.Duration
+
+	// Deleted spaces can be restored for this long, then they are purged
+	SpaceRestoreWindow time.Duration
+
+	// Accounts with these emails are made site admins on startup
+	AdminEmails []string
+
+	// Mail
+	MailDriver    string // smtp or log
+	MailFrom      string
+	MailOutboxDir string // log driver: also write each mail to this directory
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+
+	// OpenID Connect login providers, keyed by name (e.g. "google")
+	OIDCProviders map[string]OIDCProvider
+}
+
+// OIDCProvider configures one OpenID Connect identity provider. The issuer is
+// used for discovery, so any compliant provider works, including a local
+// mock issuer over plain http during development.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
 func LoadConfig() *Config {
@@ -20,12 +60,60 @@ func LoadConfig() *Config {
 		log.Println("No .env file found, relying on environment variables")
 	}
 
+	appURL := getEnv("APP_URL", "http://localhost:8080")
+
 	return &Config{
-		Port:      getEnv("PORT", "8080"),
-		DBUrl:     getEnv("DATABASE_URL", "host=localhost user=pomohub password=pomohub_secret dbname=pomohub_db port=5432 sslmode=disable"),
-		RedisUrl:  getEnv("REDIS_URL", "localhost:6379"),
-		JWTSecret: getEnv("JWT_SECRET", "super_secret_key"),
+		Port:            getEnv("PORT", "8080"),
+		DBUrl:           getEnv("DATABASE_URL", "host=localhost user=pomohub password=pomohub_secret dbname=pomohub_db port=5432 sslmode=disable"),
+		RedisUrl:        getEnv("REDIS_URL", "localhost:6379"),
+		JWTKeysDir:      getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKeyID:  getEnv("JWT_ACTIVE_KEY_ID", ""),
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		AppURL:          appURL,
+		FrontendURL:     getEnv("FRONTEND_URL", appURL),
+		MailDriver:      getEnv("MAIL_DRIVER", "log"),
+		MailFrom:        getEnv("MAIL_FROM", "PomoHub <no-reply@pomohub.app>"),
+		MailOutboxDir:   getEnv("MAIL_OUTBOX_DIR", ""),
+		SMTPHost:        getEnv("SMTP_HOST", ""),
+		SMTPPort:        getEnv("SMTP_PORT", "587"),
+		SMTPUsername:    getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
+		OIDCProviders:   loadOIDCProviders(appURL),
+
+		AccountDeletionGrace: getEnvDuration("ACCOUNT_DELETION_GRACE", 14*24*time.Hour),
+		SpaceRestoreWindow:   getEnvDuration("SPACE_RESTORE_WINDOW", 30*24*time.Hour),
+		AdminEmails:          getEnvList("ADMIN_EMAILS"),
+	}
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS (comma separated names) and, for
+// each name, OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL
+// and _SCOPES.
+func loadOIDCProviders(appURL string) map[string]OIDCProvider {
+	providers := make(map[string]OIDCProvider)
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimRight(getEnv(prefix+"ISSUER", ""), "/"),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", strings.TrimRight(appURL, "/")+"/api/v1/auth/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("OIDC provider %q is missing an issuer or client ID, skipping", name)
+			continue
+		}
+		providers[name] = provider
 	}
+	return providers
 }
 
 func getEnv(key, fallback string) string {
@@ -34,3 +122,27 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s (%q), using default %s", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvList reads a comma separated list, ignoring empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -4,9 +4,11 @@ import (
 	"log"
 	"pomodoro-habit-backend/internal/config"
 	"pomodoro-habit-backend/internal/models"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var DB *gorm.DB
@@ -20,6 +22,9 @@ func ConnectDB(cfg *config.Config) {
 
 	log.Println("Connected to Database successfully")
 
+	// Accounts from before email verification existed are trusted as they are
+	backfillVerified := !DB.Migrator().HasColumn(&models.User{}, "email_verified")
+
 	// Auto Migrate
 	log.Println("Running Migrations...")
 	if err := DB.AutoMigrate(
@@ -33,8 +38,61 @@ func ConnectDB(cfg *config.Config) {
 		&models.PomodoroSession{},
 		&models.Post{},
 		&models.Friend{},
+		&models.Session{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
+		&models.PersonalAccessToken{},
+		&models.SpaceInvite{},
+		&models.SpaceJoinRequest{},
+		&models.SpaceOwnershipTransfer{},
+		&models.SpaceTimerState{},
+		&models.SpaceAuditLog{},
+		&models.Plan{},
+		&models.SpaceTask{},
+		&models.SpaceTaskComment{},
+		&models.SpaceEvent{},
+		&models.SpaceEventRSVP{},
+		&models.SpaceChallenge{},
+		&models.SpaceTemplate{},
 	); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
+
+	if backfillVerified {
+		if err := DB.Exec("UPDATE users SET email_verified = true, email_verified_at = created_at WHERE NOT email_verified").Error; err != nil {
+			log.Fatalf("Failed to mark existing users verified: %v", err)
+		}
+	}
+
+	// Space owners used to be plain admins
+	if err := DB.Exec(`UPDATE space_members SET role = 'owner' FROM spaces
+		WHERE spaces.id = space_members.space_id AND spaces.owner_id = space_members.user_id
+		AND space_members.role <> 'owner'`).Error; err != nil {
+		log.Fatalf("Failed to migrate space owners: %v", err)
+	}
+
+	// Built-in plans
+	plans := append([]models.Plan(nil), models.DefaultPlans...)
+	if err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&plans).Error; err != nil {
+		log.Fatalf("Failed to seed plans: %v", err)
+	}
+
+	// Site admins from the configuration
+	if len(cfg.AdminEmails) > 0 {
+		if err := DB.Model(&models.User{}).Where("LOWER(email) IN ?", lowerAll(cfg.AdminEmails)).
+			Update("is_admin", true).Error; err != nil {
+			log.Printf("Failed to promote admins: %v", err)
+		}
+	}
 	log.Println("Migrations completed successfully")
 }
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(v)
+	}
+	return lowered
+}
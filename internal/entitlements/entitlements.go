@@ -0,0 +1,123 @@
+package entitlements
+
+import (
+	"errors"
+	"fmt"
+	"pomodoro-habit-backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Quotas
+const (
+	QuotaOwnedSpaces     = "owned_spaces"
+	QuotaMembersPerSpace = "members_per_space"
+)
+
+// QuotaError is returned when an action would go over a plan limit.
+type QuotaError struct {
+	Quota string
+	Limit int64
+	Plan  string
+}
+
+func (e *QuotaError) Error() string {
+	switch e.Quota {
+	case QuotaOwnedSpaces:
+		return fmt.Sprintf("The %s plan allows owning up to %d spaces", e.Plan, e.Limit)
+	default:
+		return fmt.Sprintf("Spaces on the %s plan can have up to %d members", e.Plan, e.Limit)
+	}
+}
+
+// PlanFor returns the plan of a user, falling back to the free plan when the
+// assigned one no longer exists.
+func PlanFor(tx *gorm.DB, userID uuid.UUID) (models.Plan, error) {
+	var user models.User
+	if err := tx.Select("id", "plan_name").First(&user, userID).Error; err != nil {
+		return models.Plan{}, err
+	}
+
+	var plan models.Plan
+	err := tx.First(&plan, "name = ?", user.PlanName).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && user.PlanName != models.PlanFree {
+		err = tx.First(&plan, "name = ?", models.PlanFree).Error
+	}
+	return plan, err
+}
+
+// planForSpace returns the plan of a space's owner, which sets the limits of
+// the space.
+func planForSpace(tx *gorm.DB, spaceID uuid.UUID) (models.Plan, error) {
+	var space models.Space
+	if err := tx.Select("id", "owner_id").First(&space, spaceID).Error; err != nil {
+		return models.Plan{}, err
+	}
+	return PlanFor(tx, space.OwnerID)
+}
+
+// CheckOwnedSpaces fails with a QuotaError if the user can't own another space.
+// It locks the user's row, so concurrent checks in other transactions wait
+// until the space created after this one is committed.
+func CheckOwnedSpaces(tx *gorm.DB, userID uuid.UUID) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+		return err
+	}
+	plan, err := PlanFor(tx, userID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxOwnedSpaces == 0 {
+		return nil
+	}
+
+	var owned int64
+	if err := tx.Model(&models.Space{}).Where("owner_id = ?", userID).Count(&owned).Error; err != nil {
+		return err
+	}
+	if owned >= int64(plan.MaxOwnedSpaces) {
+		return &QuotaError{Quota: QuotaOwnedSpaces, Limit: int64(plan.MaxOwnedSpaces), Plan: plan.DisplayName}
+	}
+	return nil
+}
+
+// CheckMembers fails with a QuotaError if the space can't take another member.
+// Like CheckOwnedSpaces, it locks the space's row until the transaction ends.
+func CheckMembers(tx *gorm.DB, spaceID uuid.UUID) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Space{}, spaceID).Error; err != nil {
+		return err
+	}
+	plan, err := planForSpace(tx, spaceID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxMembersPerSpace == 0 {
+		return nil
+	}
+
+	var members int64
+	if err := tx.Model(&models.SpaceMember{}).Where("space_id = ?", spaceID).Count(&members).Error; err != nil {
+		return err
+	}
+	if members >= int64(plan.MaxMembersPerSpace) {
+		return &QuotaError{Quota: QuotaMembersPerSpace, Limit: int64(plan.MaxMembersPerSpace), Plan: plan.DisplayName}
+	}
+	return nil
+}
+
+// MessageCutoff returns the oldest point in time whose chat messages are
+// still visible in a space, or nil when its history is unlimited.
+func MessageCutoff(tx *gorm.DB, spaceID uuid.UUID) (*time.Time, error) {
+	plan, err := planForSpace(tx, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.MessageRetentionDays == 0 {
+		return nil, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -plan.MessageRetentionDays)
+	return &cutoff, nil
+}
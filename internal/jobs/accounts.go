@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/entitlements"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/ws"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PurgeDeletedAccounts erases every account whose deletion grace period is over.
+func PurgeDeletedAccounts() error {
+	var users []models.User
+	if err := db.DB.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		var sessionIDs []uuid.UUID
+		db.DB.Model(&models.Session{}).Where("user_id = ?", user.ID).Pluck("id", &sessionIDs)
+
+		if err := db.DB.Transaction(func(tx *gorm.DB) error {
+			return PurgeUser(tx, user.ID)
+		}); err != nil {
+			log.Printf("Could not purge account %s: %v", user.ID, err)
+			continue
+		}
+
+		ws.GlobalHub.DisconnectSessions(sessionIDs...)
+		log.Printf("Purged account %s", user.ID)
+	}
+	return nil
+}
+
+// PurgeUser hard-deletes all personal data of a user and anonymizes the user
+// row. The row itself is kept (soft deleted) because spaces and other
+// shared records still reference it by foreign key.
+func PurgeUser(tx *gorm.DB, userID uuid.UUID) error {
+	// Spaces the user owns are handed over to another member, or go away with
+	// them if nobody can take them over
+	var ownedSpaceIDs []uuid.UUID
+	if err := tx.Model(&models.Space{}).Where("owner_id = ?", userID).Pluck("id", &ownedSpaceIDs).Error; err != nil {
+		return err
+	}
+	for _, spaceID := range ownedSpaceIDs {
+		if err := handOverSpace(tx, spaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	// The user leaves the spaces that remain; the audit log shows it as a
+	// removal by the system
+	var memberships []models.SpaceMember
+	if err := tx.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		return err
+	}
+	for _, membership := range memberships {
+		if err := models.RecordAudit(tx, membership.SpaceID, nil, models.AuditMemberRemoved, "user", &userID,
+			map[string]interface{}{"role": membership.Role}, nil); err != nil {
+			return err
+		}
+	}
+
+	// Entries written before deleted text was left out of the audit log may
+	// still quote the user's messages and comments. The model refuses
+	// updates, so go around it.
+	if err := tx.Exec(`UPDATE space_audit_logs SET "before" = "before" - 'content'
+		WHERE action IN ? AND ("before"->>'sender_id' = ? OR "before"->>'user_id' = ?)`,
+		[]string{"message_deleted", "task_comment_deleted"}, userID.String(), userID.String()).Error; err != nil {
+		return err
+	}
+
+	// Templates of other users drop the user's membership. Their pinned
+	// messages go with the messages themselves.
+	if err := tx.Exec(`UPDATE space_templates SET settings = jsonb_set(settings,
+		'{members}', COALESCE((SELECT jsonb_agg(member) FROM jsonb_array_elements(settings->'members') member WHERE member->>'user_id' <> @user), '[]'::jsonb))
+		WHERE settings->'members' @> jsonb_build_array(jsonb_build_object('user_id', CAST(@user AS text)))`,
+		sql.Named("user", userID.String())).Error; err != nil {
+		return err
+	}
+
+	var habitIDs []uuid.UUID
+	if err := tx.Unscoped().Model(&models.Habit{}).Where("user_id = ?", userID).Pluck("id", &habitIDs).Error; err != nil {
+		return err
+	}
+	if len(habitIDs) > 0 {
+		if err := tx.Where("habit_id IN ?", habitIDs).Delete(&models.HabitLog{}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Rows that belong to the user alone, matched with the @user named argument
+	deletions := []struct {
+		model interface{}
+		query string
+	}{
+		{&models.SpaceMember{}, "user_id = @user"},
+		{&models.Message{}, "sender_id = @user OR original_sender_id = @user"},
+		{&models.Post{}, "user_id = @user"},
+		{&models.Todo{}, "user_id = @user"},
+		{&models.Habit{}, "user_id = @user"},
+		{&models.PomodoroSession{}, "user_id = @user"},
+		{&models.Friend{}, "user_id = @user OR friend_id = @user"},
+		{&models.Session{}, "user_id = @user"},
+		{&models.PasswordResetToken{}, "user_id = @user"},
+		{&models.RecoveryCode{}, "user_id = @user"},
+		{&models.UserIdentity{}, "user_id = @user"},
+		{&models.PersonalAccessToken{}, "user_id = @user"},
+		{&models.SpaceJoinRequest{}, "user_id = @user"},
+		{&models.SpaceOwnershipTransfer{}, "from_user_id = @user OR to_user_id = @user"},
+		{&models.SpaceTaskComment{}, "user_id = @user"},
+		{&models.SpaceEventRSVP{}, "user_id = @user"},
+		{&models.SpaceInvite{}, "invitee_id = @user"},
+		{&models.SpaceTemplate{}, "owner_id = @user"},
+	}
+	for _, d := range deletions {
+		if err := tx.Unscoped().Where(d.query, sql.Named("user", userID)).Delete(d.model).Error; err != nil {
+			return err
+		}
+	}
+
+	// Sessions the user scheduled stay on the calendar under the space owner
+	if err := tx.Exec(`UPDATE space_events SET created_by_id = spaces.owner_id FROM spaces
+		WHERE spaces.id = space_events.space_id AND space_events.created_by_id = ? AND spaces.owner_id <> ?`,
+		userID, userID).Error; err != nil {
+		return err
+	}
+
+	// Shared tasks stay on their boards, just without the user as assignee
+	if err := tx.Unscoped().Model(&models.SpaceTask{}).Where("assignee_id = ?", userID).
+		Update("assignee_id", nil).Error; err != nil {
+		return err
+	}
+
+	// Keep a tombstone with unique placeholder values and nothing personal
+	placeholder := "deleted_" + userID.String()
+	if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"username":              placeholder,
+		"email":                 userID.String() + "@deleted.invalid",
+		"email_verified":        false,
+		"email_verified_at":     nil,
+		"password_hash":         "",
+		"totp_secret":           "",
+		"totp_enabled":          false,
+		"first_name":            "",
+		"last_name":             "",
+		"birth_date":            time.Time{},
+		"avatar_url":            "",
+		"banner_url":            "",
+		"bio":                   "",
+		"deletion_scheduled_at": nil,
+		"deleted_at":            time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// handOverSpace promotes the longest-standing admin of a space to owner,
+// falling back to moderators and then members whose plan allows owning
+// another space. Guests never inherit a space; without an heir it is purged.
+func handOverSpace(tx *gorm.DB, spaceID, ownerID uuid.UUID) error {
+	var candidates []models.SpaceMember
+	if err := tx.Where("space_id = ? AND user_id <> ? AND role IN ?", spaceID, ownerID,
+		[]string{models.SpaceRoleAdmin, models.SpaceRoleModerator, models.SpaceRoleMember}).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE role WHEN ? THEN 1 WHEN ? THEN 2 ELSE 3 END, joined_at ASC",
+			Vars: []interface{}{models.SpaceRoleAdmin, models.SpaceRoleModerator},
+		}}).
+		Find(&candidates).Error; err != nil {
+		return err
+	}
+
+	var heir *models.SpaceMember
+	for i := range candidates {
+		err := entitlements.CheckOwnedSpaces(tx, candidates[i].UserID)
+		var quotaErr *entitlements.QuotaError
+		if errors.As(err, &quotaErr) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		heir = &candidates[i]
+		break
+	}
+
+	// Nobody is left who could take it over
+	if heir == nil {
+		return PurgeSpace(tx, spaceID)
+	}
+
+	if err := tx.Model(&models.Space{}).Where("id = ?", spaceID).Update("owner_id", heir.UserID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.SpaceMember{}).Where("space_id = ? AND user_id = ?", spaceID, heir.UserID).
+		Update("role", models.SpaceRoleOwner).Error; err != nil {
+		return err
+	}
+	return models.RecordAudit(tx, spaceID, nil, models.AuditOwnershipHandedOver, "user", &heir.UserID,
+		map[string]interface{}{"owner_id": ownerID, "role": heir.Role}, map[string]interface{}{"owner_id": heir.UserID, "role": models.SpaceRoleOwner})
+}
@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"log"
+	"pomodoro-habit-backend/internal/challenges"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"time"
+)
+
+// FinalizeChallenges records the final results of challenges that have ended.
+func FinalizeChallenges() error {
+	var ended []models.SpaceChallenge
+	if err := db.DB.
+		Joins("JOIN spaces ON spaces.id = space_challenges.space_id AND spaces.deleted_at IS NULL").
+		Where("space_challenges.finalized_at IS NULL AND space_challenges.ends_at <= ?", time.Now()).
+		Find(&ended).Error; err != nil {
+		return err
+	}
+
+	for _, challenge := range ended {
+		if err := challenges.Finalize(challenge); err != nil {
+			log.Printf("Could not finalize challenge %s: %v", challenge.ID, err)
+			continue
+		}
+		log.Printf("Finalized challenge %s", challenge.ID)
+	}
+	return nil
+}
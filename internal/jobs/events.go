@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/timer"
+	"time"
+)
+
+// Sessions whose start was missed by more than this, e.g. while the server
+// was down, are not started late
+const eventStartGrace = 5 * time.Minute
+
+// StartScheduledEvents starts the shared timer of spaces whose scheduled
+// focus session has just begun, on behalf of whoever scheduled it. A timer
+// that is already in use is left alone, and so are sessions whose creator
+// may no longer control the timer.
+func StartScheduledEvents() error {
+	now := time.Now()
+	since := now.Add(-eventStartGrace)
+
+	var events []models.SpaceEvent
+	if err := db.DB.
+		Joins("JOIN spaces ON spaces.id = space_events.space_id AND spaces.deleted_at IS NULL AND spaces.archived_at IS NULL").
+		Where("space_events.auto_start_timer AND space_events.starts_at <= ?", now).
+		Where("(space_events.recurrence = '' AND space_events.starts_at >= ?) OR (space_events.recurrence <> '' AND (space_events.recurrence_until IS NULL OR space_events.recurrence_until >= ?))", since, since).
+		Find(&events).Error; err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		from := since
+		if event.LastStartedAt != nil && !event.LastStartedAt.Before(from) {
+			from = event.LastStartedAt.Add(time.Second)
+		}
+		due := event.Occurrences(from, now.Add(time.Second))
+		if len(due) == 0 {
+			continue
+		}
+		occurrence := due[len(due)-1]
+
+		// Claim the occurrence first so a failing start is not retried every minute
+		if err := db.DB.Model(&event).UpdateColumn("last_started_at", occurrence).Error; err != nil {
+			log.Printf("Could not mark session %s as started: %v", event.ID, err)
+			continue
+		}
+
+		if _, err := permissions.Authorize(event.SpaceID, event.CreatedByID, permissions.ControlTimer); err != nil {
+			log.Printf("Creator of session %s may not control the timer of space %s, not starting it", event.ID, event.SpaceID)
+			continue
+		}
+
+		_, err := timer.Apply(event.SpaceID, event.CreatedByID, timer.ActionStart)
+		if errors.Is(err, timer.ErrInvalidTransition) {
+			log.Printf("Timer of space %s already in use, not starting session %s", event.SpaceID, event.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("Could not start timer of space %s for session %s: %v", event.SpaceID, event.ID, err)
+			continue
+		}
+		log.Printf("Started timer of space %s for session %s", event.SpaceID, event.ID)
+	}
+	return nil
+}
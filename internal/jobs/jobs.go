@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Start launches the periodic background jobs.
+func Start() {
+	go every(time.Hour, "purge deleted accounts", PurgeDeletedAccounts)
+	go every(time.Hour, "purge deleted spaces", PurgeDeletedSpaces)
+	go every(time.Minute, "start scheduled sessions", StartScheduledEvents)
+	go every(time.Minute, "finalize challenges", FinalizeChallenges)
+}
+
+// every runs fn now and then on every tick, logging failures.
+func every(interval time.Duration, name string, fn func() error) {
+	run := func() {
+		if err := fn(); err != nil {
+			log.Printf("Job %q failed: %v", name, err)
+		}
+	}
+
+	run()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		run()
+	}
+}
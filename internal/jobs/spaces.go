@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"log"
+	"pomodoro-habit-backend/internal/config"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PurgeDeletedSpaces permanently removes spaces whose restore window is over,
+// together with everything that belongs to them.
+func PurgeDeletedSpaces() error {
+	cfg := config.LoadConfig()
+
+	var spaceIDs []uuid.UUID
+	if err := db.DB.Unscoped().Model(&models.Space{}).
+		Where("deleted_at IS NOT NULL AND deleted_at <= ?", time.Now().Add(-cfg.SpaceRestoreWindow)).
+		Pluck("id", &spaceIDs).Error; err != nil {
+		return err
+	}
+
+	for _, spaceID := range spaceIDs {
+		if err := db.DB.Transaction(func(tx *gorm.DB) error {
+			return PurgeSpace(tx, spaceID)
+		}); err != nil {
+			log.Printf("Could not purge space %s: %v", spaceID, err)
+			continue
+		}
+		log.Printf("Purged space %s", spaceID)
+	}
+	return nil
+}
+
+// PurgeSpace hard-deletes a space and its data. Focus sessions recorded in the
+// space stay in their members' personal history, detached from the space.
+func PurgeSpace(tx *gorm.DB, spaceID uuid.UUID) error {
+	if err := tx.Model(&models.PomodoroSession{}).Unscoped().Where("space_id = ?", spaceID).
+		Update("space_id", nil).Error; err != nil {
+		return err
+	}
+
+	// Templates belong to whoever saved them and outlive the space
+	if err := tx.Model(&models.SpaceTemplate{}).Unscoped().Where("source_space_id = ?", spaceID).
+		Update("source_space_id", nil).Error; err != nil {
+		return err
+	}
+
+	deletions := []interface{}{
+		&models.Message{},
+		&models.SpaceInvite{},
+		&models.SpaceJoinRequest{},
+		&models.SpaceOwnershipTransfer{},
+		&models.SpaceTimerState{},
+		&models.SpaceTaskComment{},
+		&models.SpaceTask{},
+		&models.SpaceEventRSVP{},
+		&models.SpaceEvent{},
+		&models.SpaceChallenge{},
+		&models.SpaceMember{},
+	}
+	for _, model := range deletions {
+		if err := tx.Unscoped().Where("space_id = ?", spaceID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	// The audit log refuses deletes through its model, purging is the only
+	// time entries are removed
+	if err := tx.Exec("DELETE FROM space_audit_logs WHERE space_id = ?", spaceID).Error; err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Where("id = ?", spaceID).Delete(&models.Space{}).Error
+}
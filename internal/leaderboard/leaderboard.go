@@ -0,0 +1,359 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Leaderboard periods, in UTC. Weeks start on Monday.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+var ErrInvalidPeriod = errors.New("unknown leaderboard period")
+
+var periods = []string{PeriodDay, PeriodWeek, PeriodMonth}
+
+// What each board ranks; both are kept for every period
+const (
+	metricMinutes  = "minutes"
+	metricSessions = "sessions"
+)
+
+// Entry is one member's standing on a leaderboard.
+type Entry struct {
+	Rank         int       `json:"rank"`
+	UserID       uuid.UUID `json:"user_id"`
+	Username     string    `json:"username"`
+	AvatarURL    string    `json:"avatar_url"`
+	FocusMinutes int64     `json:"focus_minutes"`
+	Sessions     int64     `json:"sessions"`
+}
+
+// Board is a space's leaderboard for the current period.
+type Board struct {
+	Period   string    `json:"period"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Entries  []Entry   `json:"entries"`
+}
+
+// client keeps the boards in Valkey sorted sets. Without it every board is
+// computed from Postgres.
+var client *redis.Client
+
+// Setup keeps the boards in Valkey when a client is available.
+func Setup(c *redis.Client) {
+	if c == nil {
+		log.Println("Leaderboard: computing boards from the database")
+	}
+	client = c
+}
+
+// Every change to a space's boards bumps its version. A rebuild remembers
+// the version it started from and is only stored if nothing was recorded or
+// invalidated in the meantime, so a session is neither counted twice nor
+// lost between the database read and the write.
+const versionTTL = 40 * 24 * time.Hour
+
+// increment bumps the version and only adds to boards that exist. A missing
+// board is built from Postgres on its next read, and counting into it before
+// that would make a partial board look complete.
+var increment = redis.NewScript(`
+redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+if redis.call("EXISTS", KEYS[2]) == 1 and redis.call("EXISTS", KEYS[3]) == 1 then
+	redis.call("ZINCRBY", KEYS[2], ARGV[1], ARGV[2])
+	redis.call("ZINCRBY", KEYS[3], 1, ARGV[2])
+end
+return 0
+`)
+
+// invalidate bumps the version and drops the given boards.
+var invalidate = redis.NewScript(`
+redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+for i = 2, #KEYS do
+	redis.call("DEL", KEYS[i])
+end
+return 0
+`)
+
+// storeIfUnchanged writes a rebuilt board unless the version moved on. The
+// arguments after the expected version and expiry are member, minutes and
+// sessions triples.
+var storeIfUnchanged = redis.NewScript(`
+if (redis.call("GET", KEYS[1]) or "") ~= ARGV[1] then
+	return 0
+end
+redis.call("DEL", KEYS[2], KEYS[3])
+for i = 3, #ARGV, 3 do
+	redis.call("ZADD", KEYS[2], ARGV[i + 1], ARGV[i])
+	redis.call("ZADD", KEYS[3], ARGV[i + 2], ARGV[i])
+end
+redis.call("EXPIREAT", KEYS[2], ARGV[2])
+redis.call("EXPIREAT", KEYS[3], ARGV[2])
+return 1
+`)
+
+// Bounds returns the start and end of the period containing t.
+func Bounds(period string, t time.Time) (time.Time, time.Time, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodDay:
+		return day, day.AddDate(0, 0, 1), nil
+	case PeriodWeek:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7), nil
+	case PeriodMonth:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, ErrInvalidPeriod
+}
+
+func key(spaceID uuid.UUID, period string, start time.Time, metric string) string {
+	return fmt.Sprintf("leaderboard:%s:%s:%s:%s", spaceID, period, start.Format("2006-01-02"), metric)
+}
+
+func versionKey(spaceID uuid.UUID) string {
+	return fmt.Sprintf("leaderboard:%s:version", spaceID)
+}
+
+// Record counts a completed session on the boards of every space its user
+// belongs to.
+func Record(session models.PomodoroSession) {
+	if client == nil || !session.Completed {
+		return
+	}
+
+	var spaceIDs []uuid.UUID
+	if err := db.DB.Model(&models.SpaceMember{}).Where("user_id = ?", session.UserID).Pluck("space_id", &spaceIDs).Error; err != nil {
+		log.Printf("Leaderboard: could not record session %s: %v", session.ID, err)
+		return
+	}
+	if len(spaceIDs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	// Pipelines cannot fall back from EVALSHA, so send the script itself
+	member := session.UserID.String()
+	pipe := client.Pipeline()
+	for _, spaceID := range spaceIDs {
+		for _, period := range periods {
+			start, _, _ := Bounds(period, session.CreatedAt)
+			increment.Eval(ctx, pipe, []string{
+				versionKey(spaceID),
+				key(spaceID, period, start, metricMinutes),
+				key(spaceID, period, start, metricSessions),
+			}, session.Duration, member, versionTTL.Milliseconds())
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Leaderboard: could not record session %s: %v", session.ID, err)
+	}
+}
+
+// Invalidate drops the current boards of a space so they are rebuilt, e.g.
+// after a member joined with sessions from earlier in the period. Call it
+// once the change is committed, or a rebuild may miss it.
+func Invalidate(spaceID uuid.UUID) {
+	if client == nil {
+		return
+	}
+
+	now := time.Now()
+	keys := []string{versionKey(spaceID)}
+	for _, period := range periods {
+		start, _, _ := Bounds(period, now)
+		keys = append(keys, key(spaceID, period, start, metricMinutes), key(spaceID, period, start, metricSessions))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := invalidate.Run(ctx, client, keys, versionTTL.Milliseconds()).Err(); err != nil {
+		log.Printf("Leaderboard: could not invalidate space %s: %v", spaceID, err)
+	}
+}
+
+// Get returns the current leaderboard of a space for a period, ranked by
+// focus minutes and then sessions.
+func Get(spaceID uuid.UUID, period string) (Board, error) {
+	start, end, err := Bounds(period, time.Now())
+	if err != nil {
+		return Board{}, err
+	}
+	board := Board{Period: period, StartsAt: start, EndsAt: end, Entries: []Entry{}}
+
+	// Only current members are ranked
+	var members []models.User
+	if err := db.DB.Model(&models.User{}).
+		Select("users.id", "users.username", "users.avatar_url").
+		Joins("JOIN space_members ON space_members.user_id = users.id").
+		Where("space_members.space_id = ?", spaceID).
+		Find(&members).Error; err != nil {
+		return board, err
+	}
+
+	minutes, sessions, err := fromCache(spaceID, period, start, end)
+	if err != nil {
+		if client != nil {
+			log.Printf("Leaderboard: falling back to the database for space %s: %v", spaceID, err)
+		}
+		if minutes, sessions, err = fromDatabase(spaceID, start, end); err != nil {
+			return board, err
+		}
+	}
+
+	for _, member := range members {
+		board.Entries = append(board.Entries, Entry{
+			UserID:       member.ID,
+			Username:     member.Username,
+			AvatarURL:    member.AvatarURL,
+			FocusMinutes: minutes[member.ID],
+			Sessions:     sessions[member.ID],
+		})
+	}
+	sort.SliceStable(board.Entries, func(i, j int) bool {
+		a, b := board.Entries[i], board.Entries[j]
+		if a.FocusMinutes != b.FocusMinutes {
+			return a.FocusMinutes > b.FocusMinutes
+		}
+		if a.Sessions != b.Sessions {
+			return a.Sessions > b.Sessions
+		}
+		return a.Username < b.Username
+	})
+	// Members with equal totals share a rank
+	for i := range board.Entries {
+		board.Entries[i].Rank = i + 1
+		if i > 0 && board.Entries[i].FocusMinutes == board.Entries[i-1].FocusMinutes && board.Entries[i].Sessions == board.Entries[i-1].Sessions {
+			board.Entries[i].Rank = board.Entries[i-1].Rank
+		}
+	}
+	return board, nil
+}
+
+var errNoCache = errors.New("valkey not configured")
+
+// fromCache reads a board from Valkey, building it from Postgres first when
+// it does not exist yet.
+func fromCache(spaceID uuid.UUID, period string, start, end time.Time) (map[uuid.UUID]int64, map[uuid.UUID]int64, error) {
+	if client == nil {
+		return nil, nil, errNoCache
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	minutesKey := key(spaceID, period, start, metricMinutes)
+	sessionsKey := key(spaceID, period, start, metricSessions)
+
+	built, err := client.Exists(ctx, minutesKey, sessionsKey).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	if built < 2 {
+		// Read the version before the database so that changes made during
+		// the rebuild keep it from being stored
+		version, err := client.Get(ctx, versionKey(spaceID)).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, nil, err
+		}
+		minutes, sessions, err := fromDatabase(spaceID, start, end)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := store(ctx, spaceID, version, minutesKey, sessionsKey, minutes, sessions, end); err != nil {
+			log.Printf("Leaderboard: could not cache space %s: %v", spaceID, err)
+		}
+		return minutes, sessions, nil
+	}
+
+	minutes, err := readSet(ctx, minutesKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	sessions, err := readSet(ctx, sessionsKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return minutes, sessions, nil
+}
+
+// store writes freshly computed boards if the space's version is still the
+// one they were computed at. Every current member gets an entry, even at
+// zero, so an existing set always means a complete board.
+func store(ctx context.Context, spaceID uuid.UUID, version, minutesKey, sessionsKey string, minutes, sessions map[uuid.UUID]int64, end time.Time) error {
+	var memberIDs []uuid.UUID
+	if err := db.DB.Model(&models.SpaceMember{}).Where("space_id = ?", spaceID).Pluck("user_id", &memberIDs).Error; err != nil {
+		return err
+	}
+	if len(memberIDs) == 0 {
+		return nil
+	}
+
+	// Boards outlive their period a little so late sessions still land
+	expireAt := end.Add(time.Hour)
+	args := make([]interface{}, 0, 2+3*len(memberIDs))
+	args = append(args, version, expireAt.Unix())
+	for _, id := range memberIDs {
+		args = append(args, id.String(), minutes[id], sessions[id])
+	}
+	return storeIfUnchanged.Run(ctx, client, []string{versionKey(spaceID), minutesKey, sessionsKey}, args...).Err()
+}
+
+func readSet(ctx context.Context, key string) (map[uuid.UUID]int64, error) {
+	scores, err := client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[uuid.UUID]int64, len(scores))
+	for _, z := range scores {
+		member, _ := z.Member.(string)
+		id, err := uuid.Parse(member)
+		if err != nil {
+			continue
+		}
+		values[id] = int64(z.Score)
+	}
+	return values, nil
+}
+
+// fromDatabase computes a board from the members' completed sessions.
+func fromDatabase(spaceID uuid.UUID, start, end time.Time) (map[uuid.UUID]int64, map[uuid.UUID]int64, error) {
+	var rows []struct {
+		UserID   uuid.UUID
+		Minutes  int64
+		Sessions int64
+	}
+	if err := db.DB.Model(&models.PomodoroSession{}).
+		Select("user_id, COALESCE(SUM(duration), 0) AS minutes, COUNT(*) AS sessions").
+		Where("user_id IN (?)", db.DB.Model(&models.SpaceMember{}).Select("user_id").Where("space_id = ?", spaceID)).
+		Where("completed AND created_at >= ? AND created_at < ?", start, end).
+		Group("user_id").
+		Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	minutes := make(map[uuid.UUID]int64, len(rows))
+	sessions := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		minutes[row.UserID] = row.Minutes
+		sessions[row.UserID] = row.Sessions
+	}
+	return minutes, sessions, nil
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogMailer prints emails to the server log instead of sending them. When Dir
+// is set each message is also written there as an .eml file, which is handy
+// for local development and for tests that need to read the mail.
+type LogMailer struct {
+	From string
+	Dir  string
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("Mailer: to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+
+	if m.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), recipient)
+	return os.WriteFile(filepath.Join(m.Dir, name), formatMessage(m.From, msg), 0o644)
+}
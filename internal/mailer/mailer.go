@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"log"
+	"pomodoro-habit-backend/internal/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the API handlers. It is configured by Setup.
+var Default Mailer = &LogMailer{}
+
+// Setup picks the Mailer implementation from the configuration.
+func Setup(cfg *config.Config) {
+	switch cfg.MailDriver {
+	case "smtp":
+		Default = NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+		log.Printf("Mailer: SMTP via %s:%s", cfg.SMTPHost, cfg.SMTPPort)
+	default:
+		Default = &LogMailer{From: cfg.MailFrom, Dir: cfg.MailOutboxDir}
+		log.Println("Mailer: logging emails instead of sending them")
+	}
+}
+
+// SendAsync sends the message in the background so slow mail servers don't
+// hold up the request. Failures are logged.
+func SendAsync(msg Message) {
+	m := Default
+	go func() {
+		if err := m.Send(msg); err != nil {
+			log.Printf("Mailer: failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends email through an SMTP relay using PLAIN auth.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, from.Address, []string{to.Address}, formatMessage(m.From, msg))
+}
+
+// formatMessage renders the RFC 5322 representation of a message.
+func formatMessage(from string, msg Message) []byte {
+	// Header values must not be able to inject extra headers
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	b.WriteString("From: " + header.Replace(from) + "\r\n")
+	b.WriteString("To: " + header.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + header.Replace(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken is a long-lived, scoped API credential for scripts and
+// integrations. Only the hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	TokenHash  string     `gorm:"uniqueIndex;not null" json:"-"`
+	Prefix     string     `json:"prefix"` // First characters of the token, to tell tokens apart
+	Scopes     []string   `gorm:"type:jsonb;serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (t *PersonalAccessToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// IsActive reports whether the token can still be used.
+func (t *PersonalAccessToken) IsActive() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || time.Now().Before(*t.ExpiresAt))
+}
+
+// HasScope reports whether the token was granted scope.
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
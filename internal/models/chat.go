@@ -16,6 +16,13 @@ type Message struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	PinnedAt   *time.Time `gorm:"index" json:"pinned_at,omitempty"`
+	PinnedByID *uuid.UUID `gorm:"type:uuid" json:"pinned_by_id,omitempty"`
+
+	// Author of the message this one was copied from, e.g. a pin carried over
+	// by a template. The copy itself is posted by whoever made it.
+	OriginalSenderID *uuid.UUID `gorm:"type:uuid;index" json:"original_sender_id,omitempty"`
 }
 
 func (m *Message) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Plan is a named set of limits assigned to users. A limit of 0 means
+// unlimited.
+type Plan struct {
+	Name                 string    `gorm:"primaryKey" json:"name"`
+	DisplayName          string    `gorm:"not null" json:"display_name"`
+	MaxOwnedSpaces       int       `json:"max_owned_spaces"`
+	MaxMembersPerSpace   int       `json:"max_members_per_space"`
+	MessageRetentionDays int       `json:"message_retention_days"` // Chat history visible in spaces the user owns
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// Built-in plans, seeded on startup
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+)
+
+// DefaultPlans are created on startup if missing. Existing rows are left
+// alone so limits changed through the admin API stick. Chat history stays
+// unlimited on every plan by default, so existing spaces keep all of theirs;
+// a retention limit is opt-in through the admin API.
+var DefaultPlans = []Plan{
+	{
+		Name:                 PlanFree,
+		DisplayName:          "Free",
+		MaxOwnedSpaces:       3,
+		MaxMembersPerSpace:   25,
+		MessageRetentionDays: 0,
+	},
+	{
+		Name:                 PlanPro,
+		DisplayName:          "Pro",
+		MaxOwnedSpaces:       20,
+		MaxMembersPerSpace:   200,
+		MessageRetentionDays: 0,
+	},
+}
@@ -16,6 +16,8 @@ type PomodoroSession struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	User      User           `gorm:"foreignKey:UserID" json:"-"`
+
+	SpaceID *uuid.UUID `gorm:"type:uuid;index" json:"space_id,omitempty"` // Set for sessions recorded by a space's shared timer
 }
 
 func (p *PomodoroSession) BeforeCreate(tx *gorm.DB) (err error) {
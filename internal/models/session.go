@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is a single login of a user. Access tokens carry the session ID so
+// revoking the session invalidates them before they expire.
+type Session struct {
+	ID                uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"uniqueIndex;not null" json:"-"`
+	PreviousTokenHash string     `gorm:"index" json:"-"` // Last rotated-out refresh token, used for reuse detection
+	DeviceName        string     `json:"device_name"`
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address"`
+	LastSeenAt        time.Time  `json:"last_seen_at"`
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	User              User       `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+// IsActive reports whether the session can still be used to authenticate.
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
@@ -7,6 +7,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// Space visibilities. Listed spaces show up in discovery but joining needs an
+// admin's approval; open spaces can be joined instantly.
+const (
+	SpaceVisibilityPrivate = "private"
+	SpaceVisibilityListed  = "listed"
+	SpaceVisibilityOpen    = "open"
+)
+
+// Space member roles, from most to least privileged. See the permissions
+// package for what each role may do.
+const (
+	SpaceRoleOwner     = "owner"
+	SpaceRoleAdmin     = "admin"
+	SpaceRoleModerator = "moderator"
+	SpaceRoleMember    = "member"
+	SpaceRoleGuest     = "guest"
+)
+
 type Space struct {
 	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	Name      string         `gorm:"not null" json:"name"`
@@ -20,6 +38,17 @@ type Space struct {
 	PomodoroLongBreakDuration  int `gorm:"default:15" json:"pomodoro_long_break_duration"`
 	PomodoroRounds            int `gorm:"default:4" json:"pomodoro_rounds"`
 
+	// Discovery
+	Visibility  string   `gorm:"default:'private';index" json:"visibility"` // 'private', 'listed' or 'open'
+	Description string   `json:"description"`
+	Tags        []string `gorm:"type:jsonb;serializer:json" json:"tags"`
+
+	// Archived spaces are read-only and hidden from the space list
+	ArchivedAt *time.Time `gorm:"index" json:"archived_at,omitempty"`
+
+	// Secret part of the space's iCalendar feed URL, set on first use
+	CalendarToken *string `gorm:"uniqueIndex" json:"-"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -28,7 +57,7 @@ type Space struct {
 type SpaceMember struct {
 	SpaceID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"space_id"`
 	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
-	Role      string    `gorm:"default:'member'" json:"role"` // 'admin' or 'member'
+	Role      string    `gorm:"default:'member'" json:"role"` // 'owner', 'admin', 'moderator', 'member' or 'guest'
 	JoinedAt  time.Time `json:"joined_at"`
 	Space     Space     `gorm:"foreignKey:SpaceID" json:"-"`
 	User      User      `gorm:"foreignKey:UserID" json:"user"`
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrAuditLogImmutable = errors.New("space audit log entries cannot be changed")
+
+// Space audit actions
+const (
+	AuditMemberAdded         = "member_added"
+	AuditMemberJoined        = "member_joined"
+	AuditMemberLeft          = "member_left"
+	AuditMemberRemoved       = "member_removed"
+	AuditMemberRoleChanged   = "member_role_changed"
+	AuditSettingsUpdated     = "settings_updated"
+	AuditMessageDeleted      = "message_deleted"
+	AuditMessagePinned       = "message_pinned"
+	AuditMessageUnpinned     = "message_unpinned"
+	AuditInviteCreated       = "invite_created"
+	AuditInviteRevoked       = "invite_revoked"
+	AuditJoinRequestApproved = "join_request_approved"
+	AuditJoinRequestDenied   = "join_request_denied"
+	AuditTransferOffered     = "ownership_transfer_offered"
+	AuditTransferCancelled   = "ownership_transfer_cancelled"
+	AuditTransferAccepted    = "ownership_transfer_accepted"
+	AuditTransferDeclined    = "ownership_transfer_declined"
+	AuditOwnershipHandedOver = "ownership_handed_over"
+	AuditSpaceDeleted        = "space_deleted"
+	AuditSpaceArchived       = "space_archived"
+	AuditSpaceUnarchived     = "space_unarchived"
+	AuditSpaceRestored       = "space_restored"
+	AuditTaskDeleted         = "task_deleted"
+	AuditTaskCommentDeleted  = "task_comment_deleted"
+	AuditEventDeleted        = "event_deleted"
+	AuditCalendarReset       = "calendar_feed_reset"
+	AuditChallengeCreated    = "challenge_created"
+	AuditChallengeDeleted    = "challenge_deleted"
+)
+
+// SpaceAuditLog records who did what in a space. Entries are append-only.
+type SpaceAuditLog struct {
+	ID         uuid.UUID              `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID    uuid.UUID              `gorm:"type:uuid;not null;index:idx_audit_space_created" json:"space_id"`
+	ActorID    *uuid.UUID             `gorm:"type:uuid;index" json:"actor_id"` // nil for actions taken by the system
+	Action     string                 `gorm:"not null;index" json:"action"`
+	TargetType string                 `json:"target_type,omitempty"` // 'user', 'message', 'invite', 'join_request', 'transfer' or 'space'
+	TargetID   *uuid.UUID             `gorm:"type:uuid;index" json:"target_id,omitempty"`
+	Before     map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"before,omitempty"`
+	After      map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"after,omitempty"`
+	CreatedAt  time.Time              `gorm:"index:idx_audit_space_created" json:"created_at"`
+	Actor      *User                  `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+}
+
+func (a *SpaceAuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+func (a *SpaceAuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (a *SpaceAuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// RecordAudit appends an entry to a space's audit log, without an actor for
+// actions taken by the system. Inside a transaction the error should abort
+// it; elsewhere it is only logged since the action itself already happened.
+func RecordAudit(tx *gorm.DB, spaceID uuid.UUID, actorID *uuid.UUID, action, targetType string, targetID *uuid.UUID, before, after map[string]interface{}) error {
+	entry := SpaceAuditLog{
+		SpaceID:    spaceID,
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     before,
+		After:      after,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		log.Printf("Could not record %s in audit log of space %s: %v", action, spaceID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// What a challenge counts
+const (
+	ChallengeMetricFocusMinutes  = "focus_minutes"
+	ChallengeMetricPomodoros     = "pomodoros"
+	ChallengeMetricHabitCheckIns = "habit_checkins"
+)
+
+// SpaceChallenge is a collective goal the members of a space work towards
+// between StartsAt and EndsAt, e.g. 100 pomodoros as a team in a week.
+type SpaceChallenge struct {
+	ID          uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID     uuid.UUID         `gorm:"type:uuid;not null;index" json:"space_id"`
+	CreatedByID uuid.UUID         `gorm:"type:uuid;not null" json:"created_by_id"`
+	Title       string            `gorm:"not null" json:"title"`
+	Description string            `json:"description"`
+	Metric      string            `gorm:"not null" json:"metric"` // 'focus_minutes', 'pomodoros' or 'habit_checkins'
+	Target      int64             `gorm:"not null" json:"target"`
+	StartsAt    time.Time         `gorm:"not null" json:"starts_at"`
+	EndsAt      time.Time         `gorm:"not null;index" json:"ends_at"`
+	ReachedAt   *time.Time        `json:"reached_at,omitempty"`   // When the team first hit the target
+	FinalizedAt *time.Time        `json:"finalized_at,omitempty"` // Set once the results are final
+	Results     *ChallengeResults `gorm:"type:jsonb;serializer:json" json:"results,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt    `gorm:"index" json:"-"`
+	CreatedBy   User              `gorm:"foreignKey:CreatedByID" json:"created_by"`
+}
+
+// ChallengeResults is the progress of a challenge, and its final results
+// once it is over.
+type ChallengeResults struct {
+	Total         int64                   `json:"total"`
+	Target        int64                   `json:"target"`
+	Reached       bool                    `json:"reached"`
+	Contributions []ChallengeContribution `json:"contributions"` // Largest first
+}
+
+// ChallengeContribution is what one member added to a challenge.
+type ChallengeContribution struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Value    int64     `json:"value"`
+	Share    float64   `json:"share"` // Fraction of the total, 0 to 1
+}
+
+func (c *SpaceChallenge) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+// IsActive reports whether contributions currently count towards the challenge.
+func (c *SpaceChallenge) IsActive(now time.Time) bool {
+	return c.FinalizedAt == nil && !now.Before(c.StartsAt) && now.Before(c.EndsAt)
+}
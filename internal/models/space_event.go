@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Event recurrences
+const (
+	EventRecurrenceNone   = ""
+	EventRecurrenceDaily  = "daily"
+	EventRecurrenceWeekly = "weekly"
+)
+
+// RSVP responses
+const (
+	RSVPGoing    = "going"
+	RSVPMaybe    = "maybe"
+	RSVPDeclined = "declined"
+)
+
+// SpaceEvent is a scheduled group focus session, either one-off or repeating
+// daily or weekly at the same UTC time until RecurrenceUntil.
+type SpaceEvent struct {
+	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"space_id"`
+	CreatedByID     uuid.UUID      `gorm:"type:uuid;not null" json:"created_by_id"`
+	Title           string         `gorm:"not null" json:"title"`
+	Description     string         `json:"description"`
+	StartsAt        time.Time      `gorm:"not null;index" json:"starts_at"` // First occurrence
+	DurationMinutes int            `gorm:"not null" json:"duration_minutes"`
+	Recurrence      string         `gorm:"default:''" json:"recurrence"` // '', 'daily' or 'weekly'
+	RecurrenceUntil *time.Time     `json:"recurrence_until,omitempty"`
+	AutoStartTimer  bool           `gorm:"default:true" json:"auto_start_timer"`
+	LastStartedAt   *time.Time     `json:"-"` // Occurrence the timer was last started for
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedBy       User           `gorm:"foreignKey:CreatedByID" json:"created_by"`
+}
+
+func (e *SpaceEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
+
+// Period returns the time between occurrences, 0 for one-off events.
+func (e *SpaceEvent) Period() time.Duration {
+	switch e.Recurrence {
+	case EventRecurrenceDaily:
+		return 24 * time.Hour
+	case EventRecurrenceWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// Occurrences returns the start times of the occurrences starting in
+// [from, to).
+func (e *SpaceEvent) Occurrences(from, to time.Time) []time.Time {
+	period := e.Period()
+	if period == 0 {
+		if !e.StartsAt.Before(from) && e.StartsAt.Before(to) {
+			return []time.Time{e.StartsAt}
+		}
+		return nil
+	}
+
+	start := e.StartsAt
+	if start.Before(from) {
+		skipped := from.Sub(start) / period
+		start = start.Add(skipped * period)
+		if start.Before(from) {
+			start = start.Add(period)
+		}
+	}
+
+	var occurrences []time.Time
+	for ; start.Before(to); start = start.Add(period) {
+		if e.RecurrenceUntil != nil && start.After(*e.RecurrenceUntil) {
+			break
+		}
+		occurrences = append(occurrences, start)
+	}
+	return occurrences
+}
+
+// IsOccurrence reports whether an occurrence of the event starts at t, to the
+// second.
+func (e *SpaceEvent) IsOccurrence(t time.Time) bool {
+	return len(e.Occurrences(t, t.Add(time.Second))) > 0
+}
+
+// SpaceEventRSVP is a member's answer for one occurrence of an event.
+type SpaceEventRSVP struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID   uuid.UUID `gorm:"type:uuid;not null;index" json:"space_id"`
+	EventID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_event_rsvp" json:"event_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_event_rsvp" json:"user_id"`
+	OccursAt  time.Time `gorm:"not null;uniqueIndex:idx_event_rsvp" json:"occurs_at"`
+	Response  string    `gorm:"not null" json:"response"` // 'going', 'maybe' or 'declined'
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      User      `gorm:"foreignKey:UserID" json:"user"`
+}
+
+func (r *SpaceEventRSVP) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
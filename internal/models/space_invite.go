@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SpaceInvite is a shareable code that lets anyone holding it join a space,
+// or a single invited user when InviteeID is set.
+type SpaceInvite struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"space_id"`
+	Code        string     `gorm:"uniqueIndex;not null" json:"code"`
+	CreatedByID uuid.UUID  `gorm:"type:uuid;not null" json:"created_by_id"`
+	Role        string     `gorm:"default:'member'" json:"role"` // Role given to members joining with this invite
+	MaxUses     int        `gorm:"default:0" json:"max_uses"`    // 0 = unlimited
+	Uses        int        `gorm:"default:0" json:"uses"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Space       Space      `gorm:"foreignKey:SpaceID" json:"-"`
+	CreatedBy   User       `gorm:"foreignKey:CreatedByID" json:"created_by"`
+
+	// Set for invites addressed to one user, only they can redeem it
+	InviteeID *uuid.UUID `gorm:"type:uuid;index" json:"invitee_id,omitempty"`
+}
+
+func (i *SpaceInvite) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
+
+// IsUsable reports whether the invite can still be redeemed.
+func (i *SpaceInvite) IsUsable() bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && time.Now().After(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SpaceJoinRequest is a user's request to join a listed space, waiting for an
+// admin to approve or deny it.
+type SpaceJoinRequest struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"space_id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Message      string     `json:"message"`
+	Status       string     `gorm:"default:'pending';index" json:"status"` // 'pending', 'approved' or 'denied'
+	ReviewedByID *uuid.UUID `gorm:"type:uuid" json:"reviewed_by_id,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	User         User       `gorm:"foreignKey:UserID" json:"user"`
+}
+
+func (r *SpaceJoinRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
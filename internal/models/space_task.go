@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Task board columns
+const (
+	TaskStatusBacklog    = "backlog"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusDone       = "done"
+)
+
+// SpaceTask is a card on a space's shared task board. Position orders the
+// tasks within their status column, starting at 0.
+type SpaceTask struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"space_id"`
+	CreatedByID uuid.UUID      `gorm:"type:uuid;not null" json:"created_by_id"`
+	AssigneeID  *uuid.UUID     `gorm:"type:uuid;index" json:"assignee_id,omitempty"`
+	Title       string         `gorm:"not null" json:"title"`
+	Description string         `json:"description"`
+	Status      string         `gorm:"not null;default:'backlog'" json:"status"` // 'backlog', 'in_progress' or 'done'
+	Position    int            `gorm:"not null;default:0" json:"position"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedBy   User           `gorm:"foreignKey:CreatedByID" json:"created_by"`
+	Assignee    *User          `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
+}
+
+func (t *SpaceTask) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// SpaceTaskComment is a comment on a task of a space's board.
+type SpaceTaskComment struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"space_id"`
+	TaskID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"task_id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	Content   string         `gorm:"not null" json:"content"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	User      User           `gorm:"foreignKey:UserID" json:"user"`
+}
+
+func (c *SpaceTaskComment) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SpaceTemplate is a saved space configuration a user can create new spaces
+// from.
+type SpaceTemplate struct {
+	ID            uuid.UUID             `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OwnerID       uuid.UUID             `gorm:"type:uuid;not null;index" json:"owner_id"`
+	SourceSpaceID *uuid.UUID            `gorm:"type:uuid" json:"source_space_id,omitempty"` // Space it was saved from, if it still exists
+	Name          string                `gorm:"not null" json:"name"`
+	Settings      SpaceTemplateSettings `gorm:"type:jsonb;serializer:json" json:"settings"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt        `gorm:"index" json:"-"`
+}
+
+// SpaceTemplateSettings is everything a template carries over to new spaces.
+type SpaceTemplateSettings struct {
+	PomodoroWorkDuration       int      `json:"pomodoro_work_duration"`
+	PomodoroShortBreakDuration int      `json:"pomodoro_short_break_duration"`
+	PomodoroLongBreakDuration  int      `json:"pomodoro_long_break_duration"`
+	PomodoroRounds             int      `json:"pomodoro_rounds"`
+	Visibility                 string   `json:"visibility"`
+	Description                string   `json:"description"`
+	Tags                       []string `json:"tags"`
+
+	// Members and their roles, for inviting them again
+	Members []SpaceTemplateMember `json:"members"`
+	// Pinned messages, copied into new spaces unless deleted in the meantime
+	PinnedMessages []SpaceTemplatePin `json:"pinned_messages"`
+	// Recurring sessions, rescheduled from their next occurrence
+	Events []SpaceTemplateEvent `json:"events"`
+}
+
+type SpaceTemplateMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+type SpaceTemplatePin struct {
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+type SpaceTemplateEvent struct {
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	StartsAt        time.Time  `json:"starts_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Recurrence      string     `json:"recurrence"`
+	RecurrenceUntil *time.Time `json:"recurrence_until,omitempty"`
+	AutoStartTimer  bool       `json:"auto_start_timer"`
+}
+
+func (t *SpaceTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
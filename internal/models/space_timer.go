@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SpaceTimerState is the persisted state of a space's shared pomodoro timer,
+// so a running timer survives a server restart.
+type SpaceTimerState struct {
+	SpaceID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"space_id"`
+	Phase            string     `gorm:"not null" json:"phase"`  // 'work', 'short_break' or 'long_break'
+	Status           string     `gorm:"not null" json:"status"` // 'idle', 'running' or 'paused'
+	Round            int        `gorm:"not null" json:"round"`  // Current work round, starting at 1
+	PhaseDuration    int        `json:"phase_duration"`         // in seconds
+	PhaseStartedAt   *time.Time `json:"phase_started_at"`
+	EndsAt           *time.Time `json:"ends_at"`           // Set while running
+	RemainingSeconds int        `json:"remaining_seconds"` // Set while paused
+	UpdatedByID      *uuid.UUID `gorm:"type:uuid" json:"updated_by_id"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SpaceOwnershipTransfer is an owner's offer to hand a space over to another
+// member. Ownership only changes once the recipient accepts.
+type SpaceOwnershipTransfer struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpaceID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"space_id"`
+	FromUserID  uuid.UUID  `gorm:"type:uuid;not null" json:"from_user_id"`
+	ToUserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"to_user_id"`
+	Status      string     `gorm:"default:'pending';index" json:"status"` // 'pending', 'accepted', 'declined' or 'cancelled'
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Space       Space      `gorm:"foreignKey:SpaceID" json:"space"`
+	FromUser    User       `gorm:"foreignKey:FromUserID" json:"from_user"`
+	ToUser      User       `gorm:"foreignKey:ToUserID" json:"to_user"`
+}
+
+func (t *SpaceOwnershipTransfer) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// IsPending reports whether the transfer still awaits an answer.
+func (t *SpaceOwnershipTransfer) IsPending() bool {
+	return t.Status == "pending" && time.Now().Before(t.ExpiresAt)
+}
@@ -8,19 +8,31 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	Username     string         `gorm:"uniqueIndex;not null" json:"username"`
-	Email        string         `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash string         `json:"-"`
-	FirstName    string         `json:"first_name"`
-	LastName     string         `json:"last_name"`
-	BirthDate    time.Time      `json:"birth_date"`
-	AvatarURL    string         `json:"avatar_url"`
-	BannerURL    string         `json:"banner_url"` // New
-	Bio          string         `json:"bio"`        // New
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Username        string         `gorm:"uniqueIndex;not null" json:"username"`
+	Email           string         `gorm:"uniqueIndex;not null" json:"email"`
+	EmailVerified   bool           `gorm:"default:false" json:"email_verified"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	PasswordHash    string         `json:"-"`
+	TOTPSecret      string         `json:"-"`
+	TOTPEnabled     bool           `gorm:"default:false" json:"totp_enabled"`
+	TOTPLastStep    int64          `json:"-"` // Last accepted TOTP time step, prevents code replay
+	FirstName       string         `json:"first_name"`
+	LastName        string         `json:"last_name"`
+	BirthDate       time.Time      `json:"birth_date"`
+	AvatarURL       string         `json:"avatar_url"`
+	BannerURL       string         `json:"banner_url"` // New
+	Bio             string         `json:"bio"`        // New
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Set while a requested account deletion is in its grace period
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+
+	// Entitlements
+	PlanName string `gorm:"not null;default:'free'" json:"plan"`
+	IsAdmin  bool   `gorm:"default:false" json:"-"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
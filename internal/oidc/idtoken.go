@@ -0,0 +1,72 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Identity is the subset of ID token claims used to sign a user in.
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	GivenName         string
+	FamilyName        string
+	Picture           string
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // Some providers send "true" as a string
+	PreferredUsername string      `json:"preferred_username"`
+	GivenName         string      `json:"given_name"`
+	FamilyName        string      `json:"family_name"`
+	Picture           string      `json:"picture"`
+}
+
+// VerifyIDToken checks the signature against the provider's JWKS, the
+// issuer, audience, expiry and that the nonce matches the one we sent.
+func (p *Provider) VerifyIDToken(raw, nonce string) (*Identity, error) {
+	var claims idTokenClaims
+	token, err := jwt.ParseWithClaims(raw, &claims, p.jwks.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "EdDSA"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id token: missing subject")
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+
+	return &Identity{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     verified,
+		PreferredUsername: claims.PreferredUsername,
+		GivenName:         claims.GivenName,
+		FamilyName:        claims.FamilyName,
+		Picture:           claims.Picture,
+	}, nil
+}
@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"pomodoro-habit-backend/internal/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+
+	mutex     sync.Mutex
+	configs   = map[string]config.OIDCProvider{}
+	providers = map[string]*Provider{}
+)
+
+// Provider is a configured identity provider whose discovery document and
+// signing keys have been loaded.
+type Provider struct {
+	config.OIDCProvider
+	AuthorizationEndpoint string
+	TokenEndpoint         string
+	jwks                  *keyfunc.JWKS
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Setup registers the providers from the configuration. Discovery happens
+// lazily on first use so a provider being down doesn't block startup.
+func Setup(cfg *config.Config) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	configs = cfg.OIDCProviders
+	providers = map[string]*Provider{}
+	for name := range configs {
+		log.Printf("OIDC: provider %q enabled", name)
+	}
+}
+
+// Get returns the named provider, running discovery if needed.
+func Get(name string) (*Provider, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if p, ok := providers[name]; ok {
+		return p, nil
+	}
+	cfg, ok := configs[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	p, err := discover(cfg)
+	if err != nil {
+		return nil, err
+	}
+	providers[name] = p
+	return p, nil
+}
+
+func discover(cfg config.OIDCProvider) (*Provider, error) {
+	resp, err := httpClient.Get(cfg.Issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", cfg.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery for %s: unexpected status %d", cfg.Name, resp.StatusCode)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", cfg.Name, err)
+	}
+	if strings.TrimRight(doc.Issuer, "/") != cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery for %s: issuer mismatch %q", cfg.Name, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery for %s: incomplete discovery document", cfg.Name)
+	}
+
+	jwks, err := keyfunc.Get(doc.JWKSURI, keyfunc.Options{
+		Client:            httpClient,
+		RefreshInterval:   time.Hour,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshUnknownKID: true,
+		RefreshErrorHandler: func(err error) {
+			log.Printf("OIDC: refreshing keys for %s failed: %v", cfg.Name, err)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("oidc jwks for %s: %w", cfg.Name, err)
+	}
+
+	return &Provider{
+		OIDCProvider:          cfg,
+		AuthorizationEndpoint: doc.AuthorizationEndpoint,
+		TokenEndpoint:         doc.TokenEndpoint,
+		jwks:                  jwks,
+	}, nil
+}
+
+// AuthCodeURL builds the authorization request for the code flow with PKCE.
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("scope", strings.Join(p.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Exchange trades an authorization code for the raw ID token.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response did not include an id_token")
+	}
+	return body.IDToken, nil
+}
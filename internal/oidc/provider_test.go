@@ -0,0 +1,177 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"pomodoro-habit-backend/internal/config"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mockClientID = "pomohub-test"
+	mockKeyID    = "mock-key"
+)
+
+// mockIssuer is a minimal identity provider: discovery, JWKS and a token
+// endpoint that checks the PKCE verifier against the authorization request.
+type mockIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string // code_challenge of the last authorization request
+	nonce     string
+	audience  string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, audience: mockClientID}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": mockKeyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if r.Form.Get("code") != "valid-code" || CodeChallenge(r.Form.Get("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken(t, m.nonce)})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize plays the user approving the login request.
+func (m *mockIssuer) authorize(t *testing.T, authURL string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	m.challenge = u.Query().Get("code_challenge")
+	m.nonce = u.Query().Get("nonce")
+	m.mu.Unlock()
+}
+
+func (m *mockIssuer) idToken(t *testing.T, nonce string) string {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            "user-123",
+		"aud":            m.audience,
+		"exp":            now.Add(time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          "ada@example.com",
+		"email_verified": "true",
+		"given_name":     "Ada",
+	})
+	token.Header["kid"] = mockKeyID
+	raw, err := token.SignedString(m.key)
+	if err != nil {
+		// Also called from the token endpoint, where Fatal is not allowed
+		t.Error(err)
+	}
+	return raw
+}
+
+func setupMockProvider(t *testing.T, m *mockIssuer) *Provider {
+	t.Helper()
+	Setup(&config.Config{OIDCProviders: map[string]config.OIDCProvider{"mock": {
+		Name:        "mock",
+		Issuer:      m.URL,
+		ClientID:    mockClientID,
+		RedirectURL: "http://localhost:8080/api/v1/auth/oidc/mock/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+	}}})
+	p, err := Get("mock")
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	return p
+}
+
+func TestLoginAgainstMockProvider(t *testing.T) {
+	m := newMockIssuer(t)
+	p := setupMockProvider(t, m)
+
+	m.authorize(t, p.AuthCodeURL("state", "nonce-1", CodeChallenge("verifier")))
+	raw, err := p.Exchange(context.Background(), "valid-code", "verifier")
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	identity, err := p.VerifyIDToken(raw, "nonce-1")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if identity.Subject != "user-123" || identity.Email != "ada@example.com" || !identity.EmailVerified || identity.GivenName != "Ada" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	m := newMockIssuer(t)
+	p := setupMockProvider(t, m)
+
+	m.authorize(t, p.AuthCodeURL("state", "nonce-1", CodeChallenge("verifier")))
+	if _, err := p.Exchange(context.Background(), "valid-code", "another-verifier"); err == nil {
+		t.Error("exchange succeeded with the wrong code verifier")
+	}
+}
+
+func TestVerifyIDTokenRejectsWrongNonce(t *testing.T) {
+	m := newMockIssuer(t)
+	p := setupMockProvider(t, m)
+
+	if _, err := p.VerifyIDToken(m.idToken(t, "nonce-1"), "nonce-2"); err == nil {
+		t.Error("token with another login's nonce was accepted")
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherAudience(t *testing.T) {
+	m := newMockIssuer(t)
+	p := setupMockProvider(t, m)
+
+	m.audience = "someone-else"
+	if _, err := p.VerifyIDToken(m.idToken(t, "nonce-1"), "nonce-1"); err == nil {
+		t.Error("token issued to another client was accepted")
+	}
+}
@@ -0,0 +1,136 @@
+package permissions
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Permission is an action a space member may or may not perform.
+type Permission string
+
+const (
+	ViewSpace      Permission = "view_space"
+	SendMessages   Permission = "send_messages"
+	ControlTimer   Permission = "control_timer"
+	ManageTasks    Permission = "manage_tasks"
+	ScheduleEvents Permission = "schedule_events"
+	PinMessages    Permission = "pin_messages"
+	DeleteMessages Permission = "delete_messages"
+	DeleteTasks    Permission = "delete_tasks"
+	ManageEvents   Permission = "manage_events"
+	ManageGoals    Permission = "manage_goals"
+	ManageMembers  Permission = "manage_members"
+	EditSettings   Permission = "edit_settings"
+	ViewAudit      Permission = "view_audit"
+	DeleteSpace    Permission = "delete_space"
+	ArchiveSpace   Permission = "archive_space"
+)
+
+var (
+	ErrSpaceNotFound = errors.New("space not found")
+	ErrSpaceArchived = errors.New("space is archived")
+	ErrNotMember     = errors.New("not a member of this space")
+	ErrForbidden     = errors.New("insufficient permissions")
+)
+
+// allowedWhileArchived are the permissions that still apply to an archived,
+// read-only space.
+var allowedWhileArchived = map[Permission]bool{
+	ViewSpace:    true,
+	ViewAudit:    true,
+	ArchiveSpace: true,
+	DeleteSpace:  true,
+}
+
+// ranks orders the roles; a higher rank includes every permission of the
+// ranks below it.
+var ranks = map[string]int{
+	models.SpaceRoleGuest:     1,
+	models.SpaceRoleMember:    2,
+	models.SpaceRoleModerator: 3,
+	models.SpaceRoleAdmin:     4,
+	models.SpaceRoleOwner:     5,
+}
+
+// minimumRank is the permission matrix: the lowest role allowed each action.
+var minimumRank = map[Permission]int{
+	ViewSpace:      ranks[models.SpaceRoleGuest],
+	SendMessages:   ranks[models.SpaceRoleGuest],
+	ControlTimer:   ranks[models.SpaceRoleMember],
+	ManageTasks:    ranks[models.SpaceRoleMember],
+	ScheduleEvents: ranks[models.SpaceRoleMember],
+	PinMessages:    ranks[models.SpaceRoleModerator],
+	DeleteMessages: ranks[models.SpaceRoleModerator],
+	DeleteTasks:    ranks[models.SpaceRoleModerator],
+	ManageEvents:   ranks[models.SpaceRoleModerator],
+	ManageGoals:    ranks[models.SpaceRoleModerator],
+	ManageMembers:  ranks[models.SpaceRoleAdmin],
+	EditSettings:   ranks[models.SpaceRoleAdmin],
+	ViewAudit:      ranks[models.SpaceRoleAdmin],
+	DeleteSpace:    ranks[models.SpaceRoleOwner],
+	ArchiveSpace:   ranks[models.SpaceRoleOwner],
+}
+
+// Rank returns the rank of a role, 0 for unknown roles.
+func Rank(role string) int {
+	return ranks[role]
+}
+
+// IsValidRole reports whether role is one of the known space roles.
+func IsValidRole(role string) bool {
+	return ranks[role] > 0
+}
+
+// Can reports whether the role grants the permission.
+func Can(role string, perm Permission) bool {
+	required, ok := minimumRank[perm]
+	return ok && Rank(role) >= required
+}
+
+// Outranks reports whether a member with role actor may act on a member with
+// role target, e.g. remove them or change their role.
+func Outranks(actor, target string) bool {
+	return Rank(actor) > Rank(target)
+}
+
+// Authorize loads the user's membership of the space and checks that their
+// role grants the permission. It returns ErrSpaceNotFound, ErrNotMember,
+// ErrForbidden or ErrSpaceArchived when access is denied.
+func Authorize(spaceID, userID uuid.UUID, perm Permission) (models.SpaceMember, error) {
+	var membership models.SpaceMember
+
+	// Deleted spaces keep their members until purged, so check the space too
+	var space models.Space
+	err := db.DB.Select("id", "archived_at").First(&space, spaceID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return membership, ErrSpaceNotFound
+	}
+	if err != nil {
+		return membership, err
+	}
+
+	err = db.DB.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return membership, ErrNotMember
+	}
+	if err != nil {
+		return membership, err
+	}
+	return membership, check(membership.Role, perm, space.ArchivedAt != nil)
+}
+
+// check applies the permission matrix and the read-only rules of archived
+// spaces to a member's role.
+func check(role string, perm Permission, archived bool) error {
+	if !Can(role, perm) {
+		return ErrForbidden
+	}
+	if archived && !allowedWhileArchived[perm] {
+		return ErrSpaceArchived
+	}
+	return nil
+}
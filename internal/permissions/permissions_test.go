@@ -0,0 +1,104 @@
+package permissions
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/models"
+	"testing"
+)
+
+var allPermissions = []Permission{
+	ViewSpace, SendMessages, ControlTimer, ManageTasks, ScheduleEvents,
+	PinMessages, DeleteMessages, DeleteTasks, ManageEvents, ManageGoals,
+	ManageMembers, EditSettings, ViewAudit, DeleteSpace, ArchiveSpace,
+}
+
+var allRoles = []string{
+	models.SpaceRoleGuest,
+	models.SpaceRoleMember,
+	models.SpaceRoleModerator,
+	models.SpaceRoleAdmin,
+	models.SpaceRoleOwner,
+}
+
+// granted spells out the permission matrix role by role, independently of
+// how permissions.go encodes it.
+var granted = map[string][]Permission{
+	models.SpaceRoleGuest: {ViewSpace, SendMessages},
+	models.SpaceRoleMember: {ViewSpace, SendMessages,
+		ControlTimer, ManageTasks, ScheduleEvents},
+	models.SpaceRoleModerator: {ViewSpace, SendMessages,
+		ControlTimer, ManageTasks, ScheduleEvents,
+		PinMessages, DeleteMessages, DeleteTasks, ManageEvents, ManageGoals},
+	models.SpaceRoleAdmin: {ViewSpace, SendMessages,
+		ControlTimer, ManageTasks, ScheduleEvents,
+		PinMessages, DeleteMessages, DeleteTasks, ManageEvents, ManageGoals,
+		ManageMembers, EditSettings, ViewAudit},
+	models.SpaceRoleOwner: allPermissions,
+}
+
+func grants(role string, perm Permission) bool {
+	for _, p := range granted[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCan(t *testing.T) {
+	for _, role := range allRoles {
+		for _, perm := range allPermissions {
+			if got, want := Can(role, perm), grants(role, perm); got != want {
+				t.Errorf("Can(%s, %s) = %v, want %v", role, perm, got, want)
+			}
+		}
+	}
+}
+
+func TestCanRejectsUnknown(t *testing.T) {
+	for _, perm := range allPermissions {
+		if Can("", perm) || Can("superuser", perm) {
+			t.Errorf("unknown role was granted %s", perm)
+		}
+	}
+	if Can(models.SpaceRoleOwner, Permission("launch_rockets")) {
+		t.Error("owner was granted an unknown permission")
+	}
+}
+
+func TestOutranks(t *testing.T) {
+	for i, actor := range allRoles {
+		for j, target := range allRoles {
+			if got, want := Outranks(actor, target), i > j; got != want {
+				t.Errorf("Outranks(%s, %s) = %v, want %v", actor, target, got, want)
+			}
+		}
+		if !Outranks(actor, "unknown") {
+			t.Errorf("%s does not outrank an unknown role", actor)
+		}
+		if Outranks("unknown", actor) {
+			t.Errorf("unknown role outranks %s", actor)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	readOnly := map[Permission]bool{ViewSpace: true, ViewAudit: true, ArchiveSpace: true, DeleteSpace: true}
+
+	for _, role := range allRoles {
+		for _, perm := range allPermissions {
+			for _, archived := range []bool{false, true} {
+				var want error
+				switch {
+				case !grants(role, perm):
+					want = ErrForbidden
+				case archived && !readOnly[perm]:
+					want = ErrSpaceArchived
+				}
+				if err := check(role, perm, archived); !errors.Is(err, want) {
+					t.Errorf("check(%s, %s, archived=%v) = %v, want %v", role, perm, archived, err, want)
+				}
+			}
+		}
+	}
+}
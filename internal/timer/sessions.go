@@ -0,0 +1,70 @@
+package timer
+
+import (
+	"log"
+	"pomodoro-habit-backend/internal/challenges"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/leaderboard"
+	"pomodoro-habit-backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// recordGrace is how late a work interval may be noticed and still be
+// recorded, e.g. when the phase end fires slightly behind schedule.
+const recordGrace = time.Minute
+
+// minPresence is the share of a work interval a member has to be connected
+// for to be credited with it.
+const minPresence = 0.8
+
+// recordSessions saves a finished work interval as a completed pomodoro
+// session for every member who was connected to the space for most of it.
+func recordSessions(work models.SpaceTimerState) {
+	if work.PhaseStartedAt == nil || work.EndsAt == nil {
+		return
+	}
+	required := time.Duration(float64(work.PhaseDuration) * minPresence * float64(time.Second))
+	var userIDs []uuid.UUID
+	for userID, present := range presence(work.SpaceID, *work.PhaseStartedAt, *work.EndsAt) {
+		if present >= required {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	// Connections can outlive a membership for a moment, only count members
+	var memberIDs []uuid.UUID
+	if err := db.DB.Model(&models.SpaceMember{}).
+		Where("space_id = ? AND user_id IN ?", work.SpaceID, userIDs).
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		log.Printf("Could not record sessions of space %s: %v", work.SpaceID, err)
+		return
+	}
+	if len(memberIDs) == 0 {
+		return
+	}
+
+	spaceID := work.SpaceID
+	sessions := make([]models.PomodoroSession, 0, len(memberIDs))
+	for _, userID := range memberIDs {
+		sessions = append(sessions, models.PomodoroSession{
+			UserID:    userID,
+			SpaceID:   &spaceID,
+			Duration:  work.PhaseDuration / 60,
+			Completed: true,
+			CreatedAt: *work.EndsAt,
+		})
+	}
+	if err := db.DB.Create(&sessions).Error; err != nil {
+		log.Printf("Could not record sessions of space %s: %v", work.SpaceID, err)
+		return
+	}
+	for _, session := range sessions {
+		leaderboard.Record(session)
+	}
+	challenges.Notify(memberIDs...)
+}
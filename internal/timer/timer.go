@@ -0,0 +1,362 @@
+package timer
+
+import (
+	"errors"
+	"log"
+	"pomodoro-habit-backend/internal/db"
+	"pomodoro-habit-backend/internal/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Phases of a pomodoro cycle
+const (
+	PhaseWork       = "work"
+	PhaseShortBreak = "short_break"
+	PhaseLongBreak  = "long_break"
+)
+
+// Timer statuses
+const (
+	StatusIdle    = "idle"
+	StatusRunning = "running"
+	StatusPaused  = "paused"
+)
+
+// Timer controls
+const (
+	ActionStart  = "start"
+	ActionPause  = "pause"
+	ActionResume = "resume"
+	ActionSkip   = "skip"
+	ActionReset  = "reset"
+)
+
+var (
+	ErrInvalidAction     = errors.New("unknown timer action")
+	ErrInvalidTransition = errors.New("action not allowed in the current timer state")
+)
+
+// State is what members see of a space's timer.
+type State struct {
+	models.SpaceTimerState
+	TotalRounds int       `json:"total_rounds"`
+	ServerTime  time.Time `json:"server_time"` // Lets clients correct for clock skew
+}
+
+// Broadcaster delivers timer state changes to the members of a space.
+type Broadcaster func(spaceID uuid.UUID, state State)
+
+// Presence reports how long each user was connected to a space between two
+// points in time.
+type Presence func(spaceID uuid.UUID, from, to time.Time) map[uuid.UUID]time.Duration
+
+var (
+	registry  sync.Mutex  // Guards locks and timers, never held while talking to the database
+	locks                 = make(map[uuid.UUID]*sync.Mutex)
+	timers                = make(map[uuid.UUID]*time.Timer)
+	broadcast Broadcaster = func(uuid.UUID, State) {}
+	presence  Presence    = func(uuid.UUID, time.Time, time.Time) map[uuid.UUID]time.Duration { return nil }
+)
+
+// lock serializes changes to the timer of one space, leaving other spaces
+// alone. The caller unlocks the returned mutex.
+func lock(spaceID uuid.UUID) *sync.Mutex {
+	registry.Lock()
+	l, ok := locks[spaceID]
+	if !ok {
+		l = &sync.Mutex{}
+		locks[spaceID] = l
+	}
+	registry.Unlock()
+
+	l.Lock()
+	return l
+}
+
+// Setup sets how state changes are broadcast and how to find the members
+// present for a finished work interval.
+func Setup(b Broadcaster, p Presence) {
+	broadcast = b
+	presence = p
+}
+
+// Get returns the current timer state of a space.
+func Get(spaceID uuid.UUID) (State, error) {
+	var space models.Space
+	if err := db.DB.First(&space, spaceID).Error; err != nil {
+		return State{}, err
+	}
+
+	l := lock(spaceID)
+	defer l.Unlock()
+	state, err := load(spaceID)
+	if err != nil {
+		return State{}, err
+	}
+	return newState(state, space), nil
+}
+
+// Apply performs a control action on a space's timer on behalf of a member
+// and broadcasts the resulting state.
+func Apply(spaceID, userID uuid.UUID, action string) (State, error) {
+	var space models.Space
+	if err := db.DB.First(&space, spaceID).Error; err != nil {
+		return State{}, err
+	}
+
+	l := lock(spaceID)
+	state, err := load(spaceID)
+	if err != nil {
+		l.Unlock()
+		return State{}, err
+	}
+	if err := transition(&state, space, action, time.Now()); err != nil {
+		l.Unlock()
+		return State{}, err
+	}
+
+	state.UpdatedByID = &userID
+	if err := save(state); err != nil {
+		l.Unlock()
+		return State{}, err
+	}
+	l.Unlock()
+
+	result := newState(state, space)
+	broadcast(spaceID, result)
+	return result, nil
+}
+
+// Restore reschedules the running timers after a restart, catching up on
+// phases that ended while the server was down.
+func Restore() error {
+	var states []models.SpaceTimerState
+	if err := db.DB.Where("status = ?", StatusRunning).Find(&states).Error; err != nil {
+		return err
+	}
+	for _, state := range states {
+		l := lock(state.SpaceID)
+		schedule(state)
+		l.Unlock()
+	}
+	log.Printf("Restored %d running space timers", len(states))
+	return nil
+}
+
+// expire moves a running timer past its phase end. Stale callbacks from timers
+// that were paused or reset in the meantime are ignored.
+func expire(spaceID uuid.UUID, endsAt time.Time) {
+	l := lock(spaceID)
+	state, err := load(spaceID)
+	if err != nil || state.Status != StatusRunning || state.EndsAt == nil || !state.EndsAt.Equal(endsAt) {
+		l.Unlock()
+		return
+	}
+
+	var space models.Space
+	if err := db.DB.First(&space, spaceID).Error; err != nil {
+		// The space is gone, so is its timer
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			stop(spaceID)
+			db.DB.Delete(&models.SpaceTimerState{}, "space_id = ?", spaceID)
+		}
+		l.Unlock()
+		return
+	}
+
+	now := time.Now()
+	completed := catchUp(&state, space, now)
+	state.UpdatedByID = nil
+	if err := save(state); err != nil {
+		log.Printf("Could not advance timer of space %s: %v", spaceID, err)
+		l.Unlock()
+		return
+	}
+	l.Unlock()
+
+	for _, work := range completed {
+		// Intervals that ended while the server was down had nobody present
+		if now.Sub(*work.EndsAt) <= recordGrace {
+			recordSessions(work)
+		}
+	}
+
+	broadcast(spaceID, newState(state, space))
+}
+
+// transition performs a control action on a timer state at the given time.
+func transition(state *models.SpaceTimerState, space models.Space, action string, now time.Time) error {
+	switch action {
+	case ActionStart:
+		if state.Status != StatusIdle {
+			return ErrInvalidTransition
+		}
+		startPhase(state, space, state.Phase, now)
+	case ActionPause:
+		if state.Status != StatusRunning {
+			return ErrInvalidTransition
+		}
+		state.RemainingSeconds = int(state.EndsAt.Sub(now).Round(time.Second) / time.Second)
+		if state.RemainingSeconds < 0 {
+			state.RemainingSeconds = 0
+		}
+		state.EndsAt = nil
+		state.Status = StatusPaused
+	case ActionResume:
+		if state.Status != StatusPaused {
+			return ErrInvalidTransition
+		}
+		endsAt := now.Add(time.Duration(state.RemainingSeconds) * time.Second)
+		state.EndsAt = &endsAt
+		state.RemainingSeconds = 0
+		state.Status = StatusRunning
+	case ActionSkip:
+		running := state.Status == StatusRunning
+		advance(state, space, now)
+		if !running {
+			state.Status = StatusIdle
+			state.EndsAt = nil
+		}
+	case ActionReset:
+		*state = initialState(state.SpaceID)
+	default:
+		return ErrInvalidAction
+	}
+	return nil
+}
+
+// catchUp moves a running timer past every phase that ended by now and
+// returns the work intervals completed on the way. Phases follow each other
+// back to back, even when catching up.
+func catchUp(state *models.SpaceTimerState, space models.Space, now time.Time) []models.SpaceTimerState {
+	var completed []models.SpaceTimerState
+	for state.Status == StatusRunning && !state.EndsAt.After(now) {
+		if state.Phase == PhaseWork {
+			completed = append(completed, *state)
+		}
+		advance(state, space, *state.EndsAt)
+	}
+	return completed
+}
+
+// advance moves to the next phase, starting it at the given time: work is
+// followed by a short break, or a long break after the last round. The cycle
+// ends after the long break and the timer goes back to idle.
+func advance(state *models.SpaceTimerState, space models.Space, at time.Time) {
+	switch state.Phase {
+	case PhaseWork:
+		if state.Round >= rounds(space) {
+			startPhase(state, space, PhaseLongBreak, at)
+		} else {
+			startPhase(state, space, PhaseShortBreak, at)
+		}
+	case PhaseShortBreak:
+		state.Round++
+		startPhase(state, space, PhaseWork, at)
+	default:
+		*state = initialState(state.SpaceID)
+	}
+}
+
+func startPhase(state *models.SpaceTimerState, space models.Space, phase string, at time.Time) {
+	duration := phaseDuration(space, phase)
+	endsAt := at.Add(duration)
+	state.Phase = phase
+	state.Status = StatusRunning
+	state.PhaseDuration = int(duration / time.Second)
+	state.PhaseStartedAt = &at
+	state.EndsAt = &endsAt
+	state.RemainingSeconds = 0
+}
+
+func phaseDuration(space models.Space, phase string) time.Duration {
+	minutes := space.PomodoroWorkDuration
+	switch phase {
+	case PhaseShortBreak:
+		minutes = space.PomodoroShortBreakDuration
+	case PhaseLongBreak:
+		minutes = space.PomodoroLongBreakDuration
+	}
+	if minutes <= 0 {
+		minutes = 1
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func rounds(space models.Space) int {
+	if space.PomodoroRounds <= 0 {
+		return 1
+	}
+	return space.PomodoroRounds
+}
+
+func initialState(spaceID uuid.UUID) models.SpaceTimerState {
+	return models.SpaceTimerState{
+		SpaceID: spaceID,
+		Phase:   PhaseWork,
+		Status:  StatusIdle,
+		Round:   1,
+	}
+}
+
+func newState(state models.SpaceTimerState, space models.Space) State {
+	return State{
+		SpaceTimerState: state,
+		TotalRounds:     rounds(space),
+		ServerTime:      time.Now(),
+	}
+}
+
+// load reads the stored state of a space, idle if it never ran. Callers must
+// hold the space's lock.
+func load(spaceID uuid.UUID) (models.SpaceTimerState, error) {
+	var state models.SpaceTimerState
+	result := db.DB.Where("space_id = ?", spaceID).Limit(1).Find(&state)
+	if result.Error != nil {
+		return state, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return initialState(spaceID), nil
+	}
+	return state, nil
+}
+
+// save stores the state and (re)schedules its phase end. Callers must hold
+// the space's lock.
+func save(state models.SpaceTimerState) error {
+	if err := db.DB.Save(&state).Error; err != nil {
+		return err
+	}
+	schedule(state)
+	return nil
+}
+
+// schedule arms the phase end of a running timer, firing right away if it is
+// already due. Callers must hold the space's lock.
+func schedule(state models.SpaceTimerState) {
+	stop(state.SpaceID)
+	if state.Status != StatusRunning || state.EndsAt == nil {
+		return
+	}
+	endsAt := *state.EndsAt
+	registry.Lock()
+	timers[state.SpaceID] = time.AfterFunc(time.Until(endsAt), func() {
+		expire(state.SpaceID, endsAt)
+	})
+	registry.Unlock()
+}
+
+// stop cancels the scheduled phase end of a space. Callers must hold the
+// space's lock.
+func stop(spaceID uuid.UUID) {
+	registry.Lock()
+	defer registry.Unlock()
+	if t, ok := timers[spaceID]; ok {
+		t.Stop()
+		delete(timers, spaceID)
+	}
+}
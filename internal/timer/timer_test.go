@@ -0,0 +1,171 @@
+package timer
+
+import (
+	"errors"
+	"pomodoro-habit-backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	testSpace = models.Space{
+		PomodoroWorkDuration:       25,
+		PomodoroShortBreakDuration: 5,
+		PomodoroLongBreakDuration:  15,
+		PomodoroRounds:             2,
+	}
+	testNow = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+)
+
+func at(d time.Duration) *time.Time {
+	t := testNow.Add(d)
+	return &t
+}
+
+func running(phase string, round int, endsIn time.Duration) models.SpaceTimerState {
+	return models.SpaceTimerState{Phase: phase, Status: StatusRunning, Round: round, EndsAt: at(endsIn)}
+}
+
+func paused(phase string, round int, remaining int) models.SpaceTimerState {
+	return models.SpaceTimerState{Phase: phase, Status: StatusPaused, Round: round, RemainingSeconds: remaining}
+}
+
+func idle(phase string, round int) models.SpaceTimerState {
+	return models.SpaceTimerState{Phase: phase, Status: StatusIdle, Round: round}
+}
+
+// checkState compares the fields that make up a timer's position.
+func checkState(t *testing.T, got, want models.SpaceTimerState) {
+	t.Helper()
+	if got.Phase != want.Phase || got.Status != want.Status || got.Round != want.Round || got.RemainingSeconds != want.RemainingSeconds {
+		t.Errorf("state = %s/%s round %d remaining %d, want %s/%s round %d remaining %d",
+			got.Phase, got.Status, got.Round, got.RemainingSeconds, want.Phase, want.Status, want.Round, want.RemainingSeconds)
+	}
+	switch {
+	case (got.EndsAt == nil) != (want.EndsAt == nil):
+		t.Errorf("ends at = %v, want %v", got.EndsAt, want.EndsAt)
+	case got.EndsAt != nil && !got.EndsAt.Equal(*want.EndsAt):
+		t.Errorf("ends at = %v, want %v", *got.EndsAt, *want.EndsAt)
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		name  string
+		state models.SpaceTimerState
+		want  models.SpaceTimerState
+	}{
+		{"work to short break", running(PhaseWork, 1, 0), running(PhaseShortBreak, 1, 5*time.Minute)},
+		{"last work round to long break", running(PhaseWork, 2, 0), running(PhaseLongBreak, 2, 15*time.Minute)},
+		{"short break to next round", running(PhaseShortBreak, 1, 0), running(PhaseWork, 2, 25*time.Minute)},
+		{"long break ends the cycle", running(PhaseLongBreak, 2, 0), idle(PhaseWork, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			advance(&state, testSpace, testNow)
+			checkState(t, state, tt.want)
+		})
+	}
+}
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  models.SpaceTimerState
+		action string
+		want   models.SpaceTimerState
+		err    error
+	}{
+		{"start from idle", idle(PhaseWork, 1), ActionStart, running(PhaseWork, 1, 25*time.Minute), nil},
+		{"start a skipped-to break", idle(PhaseShortBreak, 1), ActionStart, running(PhaseShortBreak, 1, 5*time.Minute), nil},
+		{"start while running", running(PhaseWork, 1, time.Minute), ActionStart, models.SpaceTimerState{}, ErrInvalidTransition},
+		{"start while paused", paused(PhaseWork, 1, 60), ActionStart, models.SpaceTimerState{}, ErrInvalidTransition},
+
+		{"pause while running", running(PhaseWork, 1, 10*time.Minute), ActionPause, paused(PhaseWork, 1, 600), nil},
+		{"pause after the phase ended", running(PhaseWork, 1, -time.Second), ActionPause, paused(PhaseWork, 1, 0), nil},
+		{"pause while idle", idle(PhaseWork, 1), ActionPause, models.SpaceTimerState{}, ErrInvalidTransition},
+		{"pause while paused", paused(PhaseWork, 1, 60), ActionPause, models.SpaceTimerState{}, ErrInvalidTransition},
+
+		{"resume while paused", paused(PhaseShortBreak, 1, 90), ActionResume, running(PhaseShortBreak, 1, 90*time.Second), nil},
+		{"resume while idle", idle(PhaseWork, 1), ActionResume, models.SpaceTimerState{}, ErrInvalidTransition},
+		{"resume while running", running(PhaseWork, 1, time.Minute), ActionResume, models.SpaceTimerState{}, ErrInvalidTransition},
+
+		{"skip while running", running(PhaseWork, 1, 10*time.Minute), ActionSkip, running(PhaseShortBreak, 1, 5*time.Minute), nil},
+		{"skip while paused", paused(PhaseWork, 1, 60), ActionSkip, idle(PhaseShortBreak, 1), nil},
+		{"skip while idle", idle(PhaseShortBreak, 1), ActionSkip, idle(PhaseWork, 2), nil},
+		{"skip the long break", running(PhaseLongBreak, 2, time.Minute), ActionSkip, idle(PhaseWork, 1), nil},
+
+		{"reset while running", running(PhaseShortBreak, 2, time.Minute), ActionReset, idle(PhaseWork, 1), nil},
+		{"reset while paused", paused(PhaseWork, 2, 60), ActionReset, idle(PhaseWork, 1), nil},
+		{"reset while idle", idle(PhaseLongBreak, 2), ActionReset, idle(PhaseWork, 1), nil},
+
+		{"unknown action", idle(PhaseWork, 1), "rewind", models.SpaceTimerState{}, ErrInvalidAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			err := transition(&state, testSpace, tt.action, testNow)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err == nil {
+				checkState(t, state, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransitionResetKeepsSpace(t *testing.T) {
+	spaceID := uuid.New()
+	state := running(PhaseWork, 2, time.Minute)
+	state.SpaceID = spaceID
+	if err := transition(&state, testSpace, ActionReset, testNow); err != nil {
+		t.Fatal(err)
+	}
+	if state.SpaceID != spaceID {
+		t.Errorf("space ID = %s, want %s", state.SpaceID, spaceID)
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     models.SpaceTimerState
+		completed []int // Rounds of the work intervals completed on the way
+		want      models.SpaceTimerState
+	}{
+		{"not due yet", running(PhaseWork, 1, time.Second), nil, running(PhaseWork, 1, time.Second)},
+		{"due right now", running(PhaseWork, 1, 0), []int{1}, running(PhaseShortBreak, 1, 5*time.Minute)},
+		// Work ended at 08:29, the break at 08:34 and the second round at 08:59
+		{"break and work in one go", running(PhaseWork, 1, -31*time.Minute), []int{1, 2}, running(PhaseLongBreak, 2, 14*time.Minute)},
+		{"whole cycle", running(PhaseWork, 1, -46*time.Minute), []int{1, 2}, idle(PhaseWork, 1)},
+		{"only breaks", running(PhaseShortBreak, 1, -time.Minute), nil, running(PhaseWork, 2, 24*time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			completed := catchUp(&state, testSpace, testNow)
+			if len(completed) != len(tt.completed) {
+				t.Fatalf("completed %d work intervals, want %d", len(completed), len(tt.completed))
+			}
+			for i, work := range completed {
+				if work.Phase != PhaseWork || work.Round != tt.completed[i] {
+					t.Errorf("completed[%d] = %s round %d, want work round %d", i, work.Phase, work.Round, tt.completed[i])
+				}
+			}
+			checkState(t, state, tt.want)
+		})
+	}
+}
+
+func TestCatchUpKeepsPhasesBackToBack(t *testing.T) {
+	state := running(PhaseWork, 1, -3*time.Minute)
+	catchUp(&state, testSpace, testNow)
+	// The break started when the work interval ended, not when that was noticed
+	if !state.PhaseStartedAt.Equal(*at(-3 * time.Minute)) || !state.EndsAt.Equal(*at(2 * time.Minute)) {
+		t.Errorf("break from %v to %v", state.PhaseStartedAt, state.EndsAt)
+	}
+}
@@ -1,18 +1,129 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-func GenerateJWT(userID uuid.UUID, secret string) (string, error) {
+// TokenTypeAccess marks JWTs that may be used to call the API.
+const TokenTypeAccess = "access"
+
+func GenerateAccessToken(userID, sessionID uuid.UUID, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": userID.String(),
+		"sid":     sessionID.String(),
+		"typ":     TokenTypeAccess,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	}
+
+	return signToken(claims)
+}
+
+// AccessClaims identifies the user and login session behind an access token.
+type AccessClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+}
+
+// ParseAccessToken is the single verifier for access tokens, used by the HTTP
+// middleware and the WebSocket handshake alike.
+func ParseAccessToken(tokenStr string) (*AccessClaims, error) {
+	token, err := jwt.Parse(tokenStr, JWTKeyfunc, jwt.WithValidMethods(validMethods()), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid access token")
+	}
+	return AccessClaimsFromToken(token)
+}
+
+// AccessClaimsFromToken extracts the access claims of an already verified token.
+func AccessClaimsFromToken(token *jwt.Token) (*AccessClaims, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != TokenTypeAccess {
+		return nil, errors.New("invalid access token")
+	}
+
+	userIDStr, _ := claims["user_id"].(string)
+	sessionIDStr, _ := claims["sid"].(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, errors.New("invalid access token")
+	}
+	sessionID, err := uuid.Parse(sessionIDStr)
+	if err != nil {
+		return nil, errors.New("invalid access token")
+	}
+	return &AccessClaims{UserID: userID, SessionID: sessionID}, nil
+}
+
+// TokenTypeEmailVerify marks JWTs mailed out to confirm an email address.
+const TokenTypeEmailVerify = "email_verify"
+
+func GenerateEmailVerificationToken(userID uuid.UUID, email string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID.String(),
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+		"email":   email,
+		"typ":     TokenTypeEmailVerify,
+		"exp":     time.Now().Add(ttl).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return signToken(claims)
+}
+
+// ParseEmailVerificationToken validates a verification token and returns the
+// user and the address it was issued for.
+func ParseEmailVerificationToken(tokenStr string) (uuid.UUID, string, error) {
+	claims, err := parseTypedToken(tokenStr, TokenTypeEmailVerify)
+	if err != nil {
+		return uuid.Nil, "", errors.New("invalid verification token")
+	}
+	userIDStr, _ := claims["user_id"].(string)
+	email, _ := claims["email"].(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil || email == "" {
+		return uuid.Nil, "", errors.New("invalid verification token")
+	}
+	return userID, email, nil
+}
+
+// TokenTypeMFAPending marks the short-lived JWT handed out after a correct
+// password when the account still needs a second factor.
+const TokenTypeMFAPending = "mfa_pending"
+
+func GenerateMFAPendingToken(userID uuid.UUID, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID.String(),
+		"typ":     TokenTypeMFAPending,
+		"exp":     time.Now().Add(ttl).Unix(),
+	}
+
+	return signToken(claims)
+}
+
+func ParseMFAPendingToken(tokenStr string) (uuid.UUID, error) {
+	claims, err := parseTypedToken(tokenStr, TokenTypeMFAPending)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid mfa token")
+	}
+	userIDStr, _ := claims["user_id"].(string)
+	return uuid.Parse(userIDStr)
+}
+
+// parseTypedToken verifies a token and makes sure it was issued for the given
+// purpose, so e.g. a verification token can't be used as an MFA token.
+func parseTypedToken(tokenStr string, typ string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, JWTKeyfunc, jwt.WithValidMethods(validMethods()), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != typ {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
 }
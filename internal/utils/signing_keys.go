@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tokens are signed with one active private key and verified against every
+// loaded key, identified by the kid header. To rotate without downtime:
+// add the new key to the keys directory and deploy, switch the active key
+// ID once every instance knows it, and remove the old key after the longest
+// token lifetime has passed.
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer // nil for verification-only keys
+	public  crypto.PublicKey
+}
+
+var (
+	keysMutex sync.RWMutex
+	activeKey *signingKey
+	keysByID  = map[string]*signingKey{}
+)
+
+// LoadSigningKeys reads every PEM file in dir. The file name without its
+// extension is the key ID. Private keys (RSA or Ed25519, PKCS#1/PKCS#8) can
+// sign and verify; public keys (PKIX) only verify, which is how a retired
+// key stays valid until its tokens expire. Without a directory an
+// ephemeral Ed25519 key is generated, which is only suitable for development;
+// a configured directory without keys is an error.
+func LoadSigningKeys(dir, activeKeyID string) error {
+	keys := map[string]*signingKey{}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+			key, err := loadSigningKey(file, kid)
+			if err != nil {
+				return fmt.Errorf("signing key %s: %w", file, err)
+			}
+			keys[kid] = key
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("no signing keys (*.pem) found in %s", dir)
+		}
+	}
+
+	if len(keys) == 0 {
+		log.Println("WARNING: no JWT signing keys configured, generating an ephemeral key. Tokens will not survive a restart.")
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		suffix, err := GenerateOpaqueToken(6)
+		if err != nil {
+			return err
+		}
+		kid := "ephemeral-" + suffix
+		keys[kid] = &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: private, public: private.Public()}
+		activeKeyID = kid
+	}
+
+	if activeKeyID == "" {
+		// Default to the last private key by name, e.g. date-named key files
+		ids := make([]string, 0, len(keys))
+		for kid, key := range keys {
+			if key.private != nil {
+				ids = append(ids, kid)
+			}
+		}
+		sort.Strings(ids)
+		if len(ids) > 0 {
+			activeKeyID = ids[len(ids)-1]
+		}
+	}
+
+	active, ok := keys[activeKeyID]
+	if !ok || active.private == nil {
+		return fmt.Errorf("active signing key %q not found or has no private key", activeKeyID)
+	}
+
+	keysMutex.Lock()
+	defer keysMutex.Unlock()
+	keysByID = keys
+	activeKey = active
+	log.Printf("JWT: signing with key %q (%s), %d verification key(s) loaded", active.kid, active.method.Alg(), len(keys))
+	return nil
+}
+
+func loadSigningKey(file, kid string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, public: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+}
+
+// signToken signs claims with the active key and stamps its key ID.
+func signToken(claims jwt.Claims) (string, error) {
+	keysMutex.RLock()
+	key := activeKey
+	keysMutex.RUnlock()
+	if key == nil {
+		return "", errors.New("signing keys are not loaded")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// JWTKeyfunc resolves the verification key of a token by its kid header. The
+// token's algorithm must match the key's, so a public key can never be
+// abused as an HMAC secret.
+func JWTKeyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	keysMutex.RLock()
+	key, ok := keysByID[kid]
+	keysMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set (RFC 7517).
+func JWKS() map[string]interface{} {
+	keysMutex.RLock()
+	defer keysMutex.RUnlock()
+
+	ids := make([]string, 0, len(keysByID))
+	for kid := range keysByID {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+
+	keys := make([]map[string]string, 0, len(ids))
+	for _, kid := range ids {
+		key := keysByID[kid]
+		jwk := map[string]string{
+			"kid": key.kid,
+			"use": "sig",
+			"alg": key.method.Alg(),
+		}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// validMethods lists the algorithms our own tokens may be signed with.
+func validMethods() []string {
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
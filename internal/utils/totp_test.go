@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// Base32 of the RFC 6238 SHA-1 test secret "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 appendix B, SHA-1. The RFC lists 8-digit codes; 6-digit codes are
+// their last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		code, err := TOTPCode(rfcSecret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("TOTPCode at %d = %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestValidateTOTPRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		at := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(rfcSecret, v.code, at, 0)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("ValidateTOTP at %d = %d, %v, want %d, true", v.unix, step, ok, v.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	// 1111111109 is the last second of its step
+	issued := time.Unix(1111111109, 0)
+	code := "081804"
+
+	tests := []struct {
+		name string
+		at   time.Time
+		ok   bool
+	}{
+		{"same step", issued, true},
+		{"next step", issued.Add(totpPeriod * time.Second), true},
+		{"previous step", issued.Add(-totpPeriod * time.Second), true},
+		{"two steps later", issued.Add(2 * totpPeriod * time.Second), false},
+		{"two steps earlier", issued.Add(-2 * totpPeriod * time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfcSecret, code, tt.at, 0); ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsReplay(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	step, ok := ValidateTOTP(rfcSecret, "005924", at, 0)
+	if !ok {
+		t.Fatal("first use was rejected")
+	}
+
+	// The same code again, also a few seconds later within its window
+	if _, ok := ValidateTOTP(rfcSecret, "005924", at, step); ok {
+		t.Error("code was accepted twice")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "005924", at.Add(totpPeriod*time.Second), step); ok {
+		t.Error("code was accepted twice in the next step")
+	}
+
+	// An older code that is still within the window once a newer one was used
+	previous, err := TOTPCode(rfcSecret, at.Add(-totpPeriod*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ValidateTOTP(rfcSecret, previous, at, step); ok {
+		t.Error("code of an earlier step was accepted after a later one")
+	}
+
+	// The next step's code is still fine
+	next, err := TOTPCode(rfcSecret, at.Add(totpPeriod*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := ValidateTOTP(rfcSecret, next, at.Add(totpPeriod*time.Second), step); !ok || got != step+1 {
+		t.Errorf("next code = %d, %v, want %d, true", got, ok, step+1)
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCodes(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870822", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, at, 0); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+	// Surrounding whitespace from copy and paste is fine
+	if _, ok := ValidateTOTP(rfcSecret, " 287082 ", at, 0); !ok {
+		t.Error("code with whitespace was rejected")
+	}
+}
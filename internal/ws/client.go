@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+const (
+	// Time allowed to write a message to the peer
+	writeWait = 10 * time.Second
+
+	// Time allowed between pongs before a connection is considered dead
+	pongWait = 60 * time.Second
+
+	// Send pings at this interval; must be less than pongWait
+	pingPeriod = pongWait * 9 / 10
+
+	// Messages queued per client before it counts as too slow
+	sendBuffer = 64
+)
+
+// writePump is the only writer on the connection. It forwards queued
+// messages and pings the peer so connections that drop without a close
+// frame are noticed by the read deadline. It closes done when it returns,
+// after which the connection may be released.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+		close(c.done)
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the queue
+				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.Conn.WriteJSON(message); err != nil {
+				log.Printf("WS: Write error for %s: %v", c.ID, err)
+				return
+			}
+
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
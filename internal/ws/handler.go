@@ -2,13 +2,15 @@ package ws
 
 import (
 	"log"
-	"pomodoro-habit-backend/internal/config"
 	"pomodoro-habit-backend/internal/db"
 	"pomodoro-habit-backend/internal/models"
+	"pomodoro-habit-backend/internal/permissions"
+	"pomodoro-habit-backend/internal/timer"
+	"pomodoro-habit-backend/internal/utils"
+	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -38,20 +40,22 @@ func SetupWebSockets(app *fiber.App) {
 		}
 
 		// Validate Token
-		cfg := config.LoadConfig()
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := utils.ParseAccessToken(tokenStr)
+		if err != nil {
 			log.Println("WS: Invalid token")
 			c.Close()
 			return
 		}
+		userID := claims.UserID
+		sessionID := claims.SessionID
 
-		claims := token.Claims.(jwt.MapClaims)
-		userIDStr := claims["user_id"].(string)
-		userID, _ := uuid.Parse(userIDStr)
+		// Reject tokens whose login session was revoked
+		var session models.Session
+		if err := db.DB.First(&session, "id = ? AND user_id = ?", sessionID, userID).Error; err != nil || !session.IsActive() {
+			log.Println("WS: Session revoked or expired")
+			c.Close()
+			return
+		}
 
 		spaceIDStr := c.Params("spaceId")
 		spaceID, err := uuid.Parse(spaceIDStr)
@@ -62,28 +66,37 @@ func SetupWebSockets(app *fiber.App) {
 		}
 
 		// Check Space Membership
-		var membership models.SpaceMember
-		if err := db.DB.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&membership).Error; err != nil {
+		if _, err := permissions.Authorize(spaceID, userID, permissions.ViewSpace); err != nil {
 			log.Println("WS: Not a member of space")
 			c.Close()
 			return
 		}
 
 		client := &Client{
-			ID:      userID,
-			Conn:    c,
-			SpaceID: spaceID,
-			Hub:     GlobalHub,
+			ID:          userID,
+			SessionID:   sessionID,
+			Conn:        c,
+			SpaceID:     spaceID,
+			Hub:         GlobalHub,
+			ConnectedAt: time.Now(),
+			send:        make(chan WSMessage, sendBuffer),
+			done:        make(chan struct{}),
 		}
 
+		// Registering tells the others the user joined, unregistering that they left.
+		// The connection goes back to a pool once this handler returns, so wait
+		// for the write pump to let go of it first.
 		client.Hub.register <- client
 		defer func() {
 			client.Hub.unregister <- client
+			<-client.done
 		}()
+		go client.writePump()
 
-		// Notify others that user joined
-		client.Hub.BroadcastToSpace(spaceID, TypeUserJoined, map[string]string{
-			"user_id": userID.String(),
+		// A peer that vanishes without closing stops answering pings
+		c.SetReadDeadline(time.Now().Add(pongWait))
+		c.SetPongHandler(func(string) error {
+			return c.SetReadDeadline(time.Now().Add(pongWait))
 		})
 
 		for {
@@ -93,14 +106,37 @@ func SetupWebSockets(app *fiber.App) {
 				break
 			}
 
-			// Handle incoming messages if needed (e.g., live typing status, or pomodoro updates sent via WS)
-			// For simplicity, we can just broadcast it back to the room if it's a valid type
+			// Clients send commands and their own status. The timer state only
+			// ever comes from the server, so nothing is relayed as is.
 			msg.SpaceID = spaceID // Ensure space ID is correct
-			
-			// If it's a pomodoro status update
-			if msg.Type == TypePomodoroStatus {
-				// Broadcast to others
-				client.Hub.broadcast <- msg
+
+			// Shared timer controls, e.g. {"type": "timer_command", "payload": {"action": "pause"}}
+			// Roles can change while connected, so check on every command.
+			if msg.Type == TypeTimerCommand {
+				payload, _ := msg.Payload.(map[string]interface{})
+				action, _ := payload["action"].(string)
+				if _, err := permissions.Authorize(spaceID, userID, permissions.ControlTimer); err != nil {
+					continue
+				}
+				// The new state reaches everyone, this client included, through the broadcaster
+				if _, err := timer.Apply(spaceID, userID, action); err != nil {
+					log.Println("WS: Timer command failed:", err)
+				}
+			}
+
+			// Presence status, e.g. {"type": "status_update", "payload": {"status": "focusing", "remaining_seconds": 1500}}
+			if msg.Type == TypeStatusUpdate {
+				payload, _ := msg.Payload.(map[string]interface{})
+				status, _ := payload["status"].(string)
+				if status != StatusIdle && status != StatusFocusing && status != StatusOnBreak {
+					continue
+				}
+				var endsAt *time.Time
+				if remaining, ok := payload["remaining_seconds"].(float64); ok && remaining > 0 && status != StatusIdle {
+					t := time.Now().Add(time.Duration(remaining) * time.Second)
+					endsAt = &t
+				}
+				client.Hub.SetStatus(spaceID, userID, status, endsAt)
 			}
 		}
 	}))
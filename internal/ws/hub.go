@@ -2,7 +2,9 @@ package ws
 
 import (
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/google/uuid"
@@ -10,10 +12,31 @@ import (
 
 // Message types
 const (
-	TypeChatMessage    = "chat_message"
-	TypePomodoroStatus = "pomodoro_status"
-	TypeUserJoined     = "user_joined"
-	TypeUserLeft       = "user_left"
+	TypeChatMessage        = "chat_message"
+	TypeMessageDeleted     = "message_deleted"
+	TypeMessagePinned      = "message_pinned"
+	TypeMessageUnpinned    = "message_unpinned"
+	TypeTimerCommand       = "timer_command"
+	TypeTimerState         = "timer_state"
+	TypeTaskCreated        = "task_created"
+	TypeTaskUpdated        = "task_updated"
+	TypeTaskMoved          = "task_moved"
+	TypeTaskDeleted        = "task_deleted"
+	TypeTaskCommentAdded   = "task_comment_added"
+	TypeTaskCommentDeleted = "task_comment_deleted"
+	TypeChallengeProgress  = "challenge_progress"
+	TypeChallengeFinished  = "challenge_finished"
+	TypeUserJoined         = "user_joined"
+	TypeUserLeft           = "user_left"
+	TypeStatusUpdate       = "status_update" // Sent by clients to change their status
+	TypeUserStatus         = "user_status"   // Broadcast when a member's status changes
+)
+
+// Member statuses shown in the presence roster
+const (
+	StatusIdle     = "idle"
+	StatusFocusing = "focusing"
+	StatusOnBreak  = "on_break"
 )
 
 // WebSocket Message Structure
@@ -25,16 +48,50 @@ type WSMessage struct {
 
 // Client represents a connected user
 type Client struct {
-	ID      uuid.UUID
-	Conn    *websocket.Conn
-	SpaceID uuid.UUID
-	Hub     *Hub
+	ID          uuid.UUID
+	SessionID   uuid.UUID
+	Conn        *websocket.Conn
+	SpaceID     uuid.UUID
+	Hub         *Hub
+	ConnectedAt time.Time
+
+	// Outgoing messages, written by the client's write pump
+	send chan WSMessage
+
+	// Closed once the write pump has stopped touching the connection
+	done chan struct{}
+
+	// Status the user set for themselves, guarded by the hub mutex
+	status       string
+	statusEndsAt *time.Time
+}
+
+// Presence is one online member in a space's roster.
+type Presence struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	Status      string     `json:"status"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	ConnectedAt time.Time  `json:"connected_at"`
+	Connections int        `json:"connections"`
+}
+
+// Closed connections are remembered this long, enough to cover the longest
+// work interval that presence is asked about
+const historyRetention = 12 * time.Hour
+
+// span is a closed connection of a user to a space.
+type span struct {
+	userID uuid.UUID
+	from   time.Time
+	to     time.Time
 }
 
 // Hub maintains the set of active clients and broadcasts messages
 type Hub struct {
-	// Registered clients map[SpaceID]map[ClientID]*Client
-	clients    map[uuid.UUID]map[uuid.UUID]*Client
+	// Registered clients map[SpaceID]set of connections. A user may be
+	// connected to the same space from several devices at once.
+	clients    map[uuid.UUID]map[*Client]bool
+	history    map[uuid.UUID][]span // Recently closed connections per space
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan WSMessage
@@ -43,7 +100,8 @@ type Hub struct {
 
 func NewHub() *Hub {
 	return &Hub{
-		clients:    make(map[uuid.UUID]map[uuid.UUID]*Client),
+		clients:    make(map[uuid.UUID]map[*Client]bool),
+		history:    make(map[uuid.UUID][]span),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan WSMessage),
@@ -56,42 +114,100 @@ func (h *Hub) Run() {
 		case client := <-h.register:
 			h.mutex.Lock()
 			if _, ok := h.clients[client.SpaceID]; !ok {
-				h.clients[client.SpaceID] = make(map[uuid.UUID]*Client)
+				h.clients[client.SpaceID] = make(map[*Client]bool)
+			}
+			first := h.connectionCount(client.SpaceID, client.ID) == 0
+			h.clients[client.SpaceID][client] = true
+			// Only the first connection of a user makes them come online
+			if first {
+				h.deliver(WSMessage{Type: TypeUserJoined, SpaceID: client.SpaceID, Payload: map[string]string{
+					"user_id": client.ID.String(),
+				}})
 			}
-			h.clients[client.SpaceID][client.ID] = client
 			h.mutex.Unlock()
 			log.Printf("Client registered: %s in Space %s", client.ID, client.SpaceID)
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
-			if space, ok := h.clients[client.SpaceID]; ok {
-				if _, ok := space[client.ID]; ok {
-					delete(space, client.ID)
-					client.Conn.Close()
-					if len(space) == 0 {
-						delete(h.clients, client.SpaceID)
-					}
-				}
-			}
+			h.removeClient(client)
 			h.mutex.Unlock()
 			log.Printf("Client unregistered: %s", client.ID)
 
 		case message := <-h.broadcast:
-			h.mutex.RLock()
-			if clients, ok := h.clients[message.SpaceID]; ok {
-				for _, client := range clients {
-					if err := client.Conn.WriteJSON(message); err != nil {
-						log.Printf("Error sending message: %v", err)
-						client.Conn.Close()
-						delete(clients, client.ID)
-					}
-				}
-			}
-			h.mutex.RUnlock()
+			h.mutex.Lock()
+			h.deliver(message)
+			h.mutex.Unlock()
+		}
+	}
+}
+
+// deliver queues a message for every client in its space. Clients too slow
+// to keep up are dropped rather than holding up everyone else. Callers must
+// hold the lock.
+func (h *Hub) deliver(message WSMessage) {
+	var slow []*Client
+	for client := range h.clients[message.SpaceID] {
+		select {
+		case client.send <- message:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	for _, client := range slow {
+		log.Printf("Dropping slow client %s in Space %s", client.ID, client.SpaceID)
+		h.removeClient(client)
+	}
+}
+
+// removeClient forgets a connection and stops its write pump, which closes
+// the connection. When it was the user's last connection to the space the
+// others are told they left. Callers must hold the lock.
+func (h *Hub) removeClient(client *Client) {
+	space, ok := h.clients[client.SpaceID]
+	if !ok {
+		return
+	}
+	if _, ok := space[client]; ok {
+		delete(space, client)
+		close(client.send)
+		h.remember(client)
+		if h.connectionCount(client.SpaceID, client.ID) == 0 {
+			h.deliver(WSMessage{Type: TypeUserLeft, SpaceID: client.SpaceID, Payload: map[string]string{
+				"user_id": client.ID.String(),
+			}})
+		}
+		if len(space) == 0 {
+			delete(h.clients, client.SpaceID)
 		}
 	}
 }
 
+// remember keeps a closed connection for PresentDuring and forgets those
+// that are too old to matter. Callers must hold the lock.
+func (h *Hub) remember(client *Client) {
+	now := time.Now()
+	cutoff := now.Add(-historyRetention)
+	kept := h.history[client.SpaceID][:0]
+	for _, s := range h.history[client.SpaceID] {
+		if s.to.After(cutoff) {
+			kept = append(kept, s)
+		}
+	}
+	h.history[client.SpaceID] = append(kept, span{userID: client.ID, from: client.ConnectedAt, to: now})
+}
+
+// connectionCount counts a user's connections to a space. Callers must hold
+// the lock.
+func (h *Hub) connectionCount(spaceID, userID uuid.UUID) int {
+	count := 0
+	for client := range h.clients[spaceID] {
+		if client.ID == userID {
+			count++
+		}
+	}
+	return count
+}
+
 // Helper to broadcast message from API handlers
 func (h *Hub) BroadcastToSpace(spaceID uuid.UUID, msgType string, payload interface{}) {
 	h.broadcast <- WSMessage{
@@ -100,3 +216,140 @@ func (h *Hub) BroadcastToSpace(spaceID uuid.UUID, msgType string, payload interf
 		Payload: payload,
 	}
 }
+
+// PresentDuring returns how long each user was connected to a space between
+// from and to, counting overlapping connections of the same user once.
+func (h *Hub) PresentDuring(spaceID uuid.UUID, from, to time.Time) map[uuid.UUID]time.Duration {
+	h.mutex.RLock()
+	now := time.Now()
+	byUser := make(map[uuid.UUID][]span)
+	for _, s := range h.history[spaceID] {
+		byUser[s.userID] = append(byUser[s.userID], s)
+	}
+	for client := range h.clients[spaceID] {
+		byUser[client.ID] = append(byUser[client.ID], span{userID: client.ID, from: client.ConnectedAt, to: now})
+	}
+	h.mutex.RUnlock()
+
+	present := make(map[uuid.UUID]time.Duration)
+	for userID, spans := range byUser {
+		sort.Slice(spans, func(i, j int) bool { return spans[i].from.Before(spans[j].from) })
+		var total time.Duration
+		var covered time.Time // End of the time counted so far
+		for _, s := range spans {
+			start, end := s.from, s.to
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if start.Before(covered) {
+				start = covered
+			}
+			if end.After(start) {
+				total += end.Sub(start)
+				covered = end
+			}
+		}
+		if total > 0 {
+			present[userID] = total
+		}
+	}
+	return present
+}
+
+// Roster returns the online members of a space with the status they set.
+// Status is empty for members who have not set one or whose status has run
+// out, leaving it to the caller to fill in.
+func (h *Hub) Roster(spaceID uuid.UUID) []Presence {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	now := time.Now()
+	byUser := make(map[uuid.UUID]*Presence)
+	roster := []Presence{}
+	order := []uuid.UUID{}
+	for client := range h.clients[spaceID] {
+		p, ok := byUser[client.ID]
+		if !ok {
+			p = &Presence{UserID: client.ID, ConnectedAt: client.ConnectedAt}
+			byUser[client.ID] = p
+			order = append(order, client.ID)
+		}
+		p.Connections++
+		if client.ConnectedAt.Before(p.ConnectedAt) {
+			p.ConnectedAt = client.ConnectedAt
+		}
+		if client.status != "" && (client.statusEndsAt == nil || client.statusEndsAt.After(now)) {
+			p.Status = client.status
+			p.EndsAt = client.statusEndsAt
+		}
+	}
+	for _, id := range order {
+		roster = append(roster, *byUser[id])
+	}
+	return roster
+}
+
+// SetStatus changes a user's status on all their connections to a space and
+// tells the other members.
+func (h *Hub) SetStatus(spaceID, userID uuid.UUID, status string, endsAt *time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for client := range h.clients[spaceID] {
+		if client.ID == userID {
+			client.status = status
+			client.statusEndsAt = endsAt
+		}
+	}
+	h.deliver(WSMessage{Type: TypeUserStatus, SpaceID: spaceID, Payload: Presence{
+		UserID:      userID,
+		Status:      status,
+		EndsAt:      endsAt,
+		Connections: h.connectionCount(spaceID, userID),
+	}})
+}
+
+// DisconnectSessions closes every live connection opened with one of the
+// given login sessions, e.g. after the user revoked them.
+func (h *Hub) DisconnectSessions(sessionIDs ...uuid.UUID) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+	revoked := make(map[uuid.UUID]bool, len(sessionIDs))
+	for _, id := range sessionIDs {
+		revoked[id] = true
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, space := range h.clients {
+		for client := range space {
+			if revoked[client.SessionID] {
+				h.removeClient(client)
+			}
+		}
+	}
+}
+
+// DisconnectMember closes the live connections of users to a space, e.g.
+// after they were removed from it or the space was deleted.
+func (h *Hub) DisconnectMember(spaceID uuid.UUID, userIDs ...uuid.UUID) {
+	if len(userIDs) == 0 {
+		return
+	}
+	removed := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		removed[id] = true
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for client := range h.clients[spaceID] {
+		if removed[client.ID] {
+			h.removeClient(client)
+		}
+	}
+}